@@ -1,216 +1,204 @@
 package tomv
 
 import (
-	"strconv"
-	"strings"
 	"time"
 )
 
 // Get retrieves a string value by key, panics if not found
-func Get(key string) string {
-	value, err := getValue(key)
+func (c *Config) Get(key string) string {
+	return getAs(c.getEntry, key, asString)
+}
+
+// GetInt retrieves an integer value by key, panics if not found or invalid
+func (c *Config) GetInt(key string) int {
+	return getAs(c.getEntry, key, asInt)
+}
+
+// GetBool retrieves a boolean value by key, panics if not found or invalid
+func (c *Config) GetBool(key string) bool {
+	return getAs(c.getEntry, key, asBool)
+}
+
+// GetFloat retrieves a float64 value by key, panics if not found or invalid
+func (c *Config) GetFloat(key string) float64 {
+	return getAs(c.getEntry, key, asFloat)
+}
+
+// GetDuration retrieves a time.Duration value by key, panics if not found or invalid
+func (c *Config) GetDuration(key string) time.Duration {
+	return getAs(c.getEntry, key, asDuration)
+}
+
+// GetTime retrieves a TOML datetime value by key, panics if not found or invalid
+func (c *Config) GetTime(key string) time.Time {
+	return getAs(c.getEntry, key, asTime)
+}
+
+// GetStringSlice retrieves a TOML array or comma-separated string as a slice, panics if not found
+func (c *Config) GetStringSlice(key string) []string {
+	return getAs(c.getEntry, key, asStringSlice)
+}
+
+// GetIntSlice retrieves a TOML integer array or comma-separated string as an int slice, panics if not found or invalid
+func (c *Config) GetIntSlice(key string) []int {
+	return getAs(c.getEntry, key, asIntSlice)
+}
+
+// GetSecret retrieves a value by key as a Secret that prints as ****, panics if not found
+func (c *Config) GetSecret(key string) Secret {
+	return getAs(c.getEntry, key, asSecret)
+}
+
+// GetOr retrieves a string value by key, returns default if not found
+func (c *Config) GetOr(key string, defaultValue string) string {
+	return getAsOr(c.getEntry, key, asString, defaultValue)
+}
+
+// GetIntOr retrieves an integer value by key, returns default if not found or invalid
+func (c *Config) GetIntOr(key string, defaultValue int) int {
+	return getAsOr(c.getEntry, key, asInt, defaultValue)
+}
+
+// GetBoolOr retrieves a boolean value by key, returns default if not found or invalid
+func (c *Config) GetBoolOr(key string, defaultValue bool) bool {
+	return getAsOr(c.getEntry, key, asBool, defaultValue)
+}
+
+// GetFloatOr retrieves a float64 value by key, returns default if not found or invalid
+func (c *Config) GetFloatOr(key string, defaultValue float64) float64 {
+	return getAsOr(c.getEntry, key, asFloat, defaultValue)
+}
+
+// GetDurationOr retrieves a time.Duration value by key, returns default if not found or invalid
+func (c *Config) GetDurationOr(key string, defaultValue time.Duration) time.Duration {
+	return getAsOr(c.getEntry, key, asDuration, defaultValue)
+}
+
+// GetTimeOr retrieves a TOML datetime value by key, returns default if not found or invalid
+func (c *Config) GetTimeOr(key string, defaultValue time.Time) time.Time {
+	return getAsOr(c.getEntry, key, asTime, defaultValue)
+}
+
+// GetStringSliceOr retrieves a TOML array or comma-separated string as a slice, returns default if not found
+func (c *Config) GetStringSliceOr(key string, defaultValue []string) []string {
+	return getAsOr(c.getEntry, key, asStringSlice, defaultValue)
+}
+
+// GetIntSliceOr retrieves a TOML integer array or comma-separated string as an int slice, returns default if not found or invalid
+func (c *Config) GetIntSliceOr(key string, defaultValue []int) []int {
+	return getAsOr(c.getEntry, key, asIntSlice, defaultValue)
+}
+
+// Exists checks if a variable exists without retrieving its value
+func (c *Config) Exists(key string) bool {
+	return c.getEntry(key).err == nil
+}
+
+// getAs retrieves a value converted by convert, panics if not found or invalid
+func getAs[T any](r reader, key string, convert func(key string, value interface{}) (T, error)) T {
+	value, err := lookupAs(r, key, convert)
 	if err != nil {
 		panic(err)
 	}
 	return value
 }
 
+// getAsOr retrieves a value converted by convert, returns defaultValue if not found or invalid
+func getAsOr[T any](r reader, key string, convert func(key string, value interface{}) (T, error), defaultValue T) T {
+	if value, err := lookupAs(r, key, convert); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// Package-level functions operate on the default Config
+
+// Get retrieves a string value by key, panics if not found
+func Get(key string) string {
+	return defaultConfig.Get(key)
+}
+
 // GetInt retrieves an integer value by key, panics if not found or invalid
 func GetInt(key string) int {
-	value := Get(key)
-	result, err := strconv.Atoi(value)
-	if err != nil {
-		panic("variable \"" + key + "\" is not a valid integer: " + value)
-	}
-	return result
+	return defaultConfig.GetInt(key)
 }
 
 // GetBool retrieves a boolean value by key, panics if not found or invalid
 func GetBool(key string) bool {
-	value := Get(key)
-	switch value {
-	case "true", "True", "TRUE", "yes", "Yes", "YES", "1":
-		return true
-	case "false", "False", "FALSE", "no", "No", "NO", "0":
-		return false
-	default:
-		panic("variable \"" + key + "\" is not a valid boolean: " + value)
-	}
+	return defaultConfig.GetBool(key)
 }
 
 // GetFloat retrieves a float64 value by key, panics if not found or invalid
 func GetFloat(key string) float64 {
-	value := Get(key)
-	result, err := strconv.ParseFloat(value, 64)
-	if err != nil {
-		panic("variable \"" + key + "\" is not a valid float: " + value)
-	}
-	return result
+	return defaultConfig.GetFloat(key)
 }
 
 // GetDuration retrieves a time.Duration value by key, panics if not found or invalid
 func GetDuration(key string) time.Duration {
-	value := Get(key)
-	result, err := time.ParseDuration(value)
-	if err != nil {
-		panic("variable \"" + key + "\" is not a valid duration: " + value)
-	}
-	return result
+	return defaultConfig.GetDuration(key)
 }
 
-// GetStringSlice retrieves a comma-separated string value as a slice, panics if not found
-func GetStringSlice(key string) []string {
-	value := Get(key)
-	if value == "" {
-		return []string{}
-	}
+// GetTime retrieves a TOML datetime value by key, panics if not found or invalid
+func GetTime(key string) time.Time {
+	return defaultConfig.GetTime(key)
+}
 
-	// Split on comma and trim whitespace
-	parts := strings.Split(value, ",")
-	result := make([]string, len(parts))
-	for i, part := range parts {
-		result[i] = strings.TrimSpace(part)
-	}
-	return result
+// GetStringSlice retrieves a TOML array or comma-separated string as a slice, panics if not found
+func GetStringSlice(key string) []string {
+	return defaultConfig.GetStringSlice(key)
 }
 
-// GetIntSlice retrieves a comma-separated string value as an int slice, panics if not found or invalid
+// GetIntSlice retrieves a TOML integer array or comma-separated string as an int slice, panics if not found or invalid
 func GetIntSlice(key string) []int {
-	stringSlice := GetStringSlice(key)
-	result := make([]int, len(stringSlice))
-
-	for i, str := range stringSlice {
-		if str == "" {
-			continue // Skip empty strings
-		}
-		num, err := strconv.Atoi(str)
-		if err != nil {
-			panic("variable \"" + key + "\" contains invalid integer: " + str)
-		}
-		result[i] = num
-	}
-	return result
+	return defaultConfig.GetIntSlice(key)
+}
+
+// GetSecret retrieves a value from the default Config as a Secret, panics if not found
+func GetSecret(key string) Secret {
+	return defaultConfig.GetSecret(key)
 }
 
 // GetOr retrieves a string value by key, returns default if not found
 func GetOr(key string, defaultValue string) string {
-	value, err := getValue(key)
-	if err != nil {
-		return defaultValue
-	}
-	return value
+	return defaultConfig.GetOr(key, defaultValue)
 }
 
 // GetIntOr retrieves an integer value by key, returns default if not found or invalid
 func GetIntOr(key string, defaultValue int) int {
-	value, err := getValue(key)
-	if err != nil {
-		return defaultValue
-	}
-	result, err := strconv.Atoi(value)
-	if err != nil {
-		return defaultValue
-	}
-	return result
+	return defaultConfig.GetIntOr(key, defaultValue)
 }
 
 // GetBoolOr retrieves a boolean value by key, returns default if not found or invalid
 func GetBoolOr(key string, defaultValue bool) bool {
-	value, err := getValue(key)
-	if err != nil {
-		return defaultValue
-	}
-	switch value {
-	case "true", "True", "TRUE", "yes", "Yes", "YES", "1":
-		return true
-	case "false", "False", "FALSE", "no", "No", "NO", "0":
-		return false
-	default:
-		return defaultValue
-	}
+	return defaultConfig.GetBoolOr(key, defaultValue)
 }
 
 // GetFloatOr retrieves a float64 value by key, returns default if not found or invalid
 func GetFloatOr(key string, defaultValue float64) float64 {
-	value, err := getValue(key)
-	if err != nil {
-		return defaultValue
-	}
-	result, err := strconv.ParseFloat(value, 64)
-	if err != nil {
-		return defaultValue
-	}
-	return result
+	return defaultConfig.GetFloatOr(key, defaultValue)
 }
 
 // GetDurationOr retrieves a time.Duration value by key, returns default if not found or invalid
 func GetDurationOr(key string, defaultValue time.Duration) time.Duration {
-	value, err := getValue(key)
-	if err != nil {
-		return defaultValue
-	}
-	result, err := time.ParseDuration(value)
-	if err != nil {
-		return defaultValue
-	}
-	return result
+	return defaultConfig.GetDurationOr(key, defaultValue)
 }
 
-// GetStringSliceOr retrieves a comma-separated string value as a slice, returns default if not found
-func GetStringSliceOr(key string, defaultValue []string) []string {
-	value, err := getValue(key)
-	if err != nil {
-		return defaultValue
-	}
-	if value == "" {
-		return []string{}
-	}
+// GetTimeOr retrieves a TOML datetime value by key, returns default if not found or invalid
+func GetTimeOr(key string, defaultValue time.Time) time.Time {
+	return defaultConfig.GetTimeOr(key, defaultValue)
+}
 
-	// Split on comma and trim whitespace
-	parts := strings.Split(value, ",")
-	result := make([]string, len(parts))
-	for i, part := range parts {
-		result[i] = strings.TrimSpace(part)
-	}
-	return result
+// GetStringSliceOr retrieves a TOML array or comma-separated string as a slice, returns default if not found
+func GetStringSliceOr(key string, defaultValue []string) []string {
+	return defaultConfig.GetStringSliceOr(key, defaultValue)
 }
 
-// GetIntSliceOr retrieves a comma-separated string value as an int slice, returns default if not found or invalid
+// GetIntSliceOr retrieves a TOML integer array or comma-separated string as an int slice, returns default if not found or invalid
 func GetIntSliceOr(key string, defaultValue []int) []int {
-	value, err := getValue(key)
-	if err != nil {
-		return defaultValue
-	}
-	if value == "" {
-		return []int{}
-	}
-
-	// Split on comma and trim whitespace
-	parts := strings.Split(value, ",")
-	result := make([]int, len(parts))
-
-	for i, str := range parts {
-		str = strings.TrimSpace(str)
-		if str == "" {
-			continue // Skip empty strings
-		}
-		num, err := strconv.Atoi(str)
-		if err != nil {
-			return defaultValue // Return default if any conversion fails
-		}
-		result[i] = num
-	}
-	return result
+	return defaultConfig.GetIntSliceOr(key, defaultValue)
 }
 
 // Exists checks if a variable exists without retrieving its value
 func Exists(key string) bool {
-	_, err := getValue(key)
-	return err == nil
-}
-
-// getValue is the internal function that handles the actual value retrieval
-// This will be implemented by discovery.go and cache.go
-func getValue(key string) (string, error) {
-	return getValueFromCache(key)
+	return defaultConfig.Exists(key)
 }
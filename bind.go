@@ -0,0 +1,436 @@
+package tomv
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError reports a struct field that could not be bound
+type FieldError struct {
+	Field    string   `json:"field"`              // Go field path, e.g. Server.Port
+	Key      string   `json:"key"`                // tomv key the field was bound to
+	Position Position `json:"position,omitempty"` // Where the key is defined, zero if it is missing
+	Err      error    `json:"-"`
+}
+
+func (e *FieldError) Error() string {
+	if e.Position.Line > 0 {
+		return fmt.Sprintf("%s (%s at %s): %v", e.Field, e.Key, e.Position, e.Err)
+	}
+	return fmt.Sprintf("%s (%s): %v", e.Field, e.Key, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// BindError aggregates every field that failed during Bind or Unmarshal
+type BindError struct {
+	Errors []*FieldError `json:"errors"`
+}
+
+func (e *BindError) Error() string {
+	errorMsg := fmt.Sprintf("failed to bind %d field(s):", len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		errorMsg += "\n- " + fieldErr.Error()
+	}
+	return errorMsg
+}
+
+// Unwrap exposes the field errors to errors.Is and errors.As
+func (e *BindError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		errs[i] = fieldErr
+	}
+	return errs
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Bind fills a struct from the resolved configuration using `tomv` field tags
+//
+//	type ServerConfig struct {
+//		Port    int           `tomv:"server.port,default=8080"`
+//		Host    string        `tomv:"server.host,required"`
+//		Timeout time.Duration `tomv:"server.timeout,default=30s"`
+//	}
+func (c *Config) Bind(target interface{}) error {
+	return c.Unmarshal("", target)
+}
+
+// Unmarshal fills a struct from the resolved configuration, with tag keys relative to prefix
+func (c *Config) Unmarshal(prefix string, target interface{}) error {
+	return unmarshalSnapshot(c.loadSnapshot(), prefix, target)
+}
+
+// unmarshalSnapshot fills a struct from one resolved snapshot
+func unmarshalSnapshot(snap *snapshot, prefix string, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("tomv: bind target must be a non-nil pointer to a struct, got %T", target)
+	}
+
+	if snap.err != nil {
+		return snap.err
+	}
+
+	b := &binder{}
+	b.bindStruct(v.Elem(), prefix, "", func(key string) (interface{}, error) {
+		return findRawValue(snap.files, key, snap.explicitPrefixes)
+	})
+	if len(b.errors) > 0 {
+		for _, fieldErr := range b.errors {
+			fileData, localKey, err := locateKey(snap.files, fieldErr.Key, snap.explicitPrefixes)
+			if err != nil {
+				continue // Missing keys have no value or position
+			}
+			fieldErr.Position, _ = fileData.position(localKey)
+			var mismatch *mismatchError
+			if errors.As(fieldErr.Err, &mismatch) {
+				// The failing value may be an item or field below the bound key
+				if fileData, localKey, err := locateKey(snap.files, mismatch.key, snap.explicitPrefixes); err == nil {
+					mismatch.value = fileData.masked(localKey, mismatch.value)
+				}
+			}
+		}
+		return &BindError{Errors: b.errors}
+	}
+	return nil
+}
+
+// lookupFunc resolves a full tomv key to its raw value
+type lookupFunc func(key string) (interface{}, error)
+
+// binder walks a struct and collects every field error instead of stopping at the first
+type binder struct {
+	errors []*FieldError
+}
+
+// fieldTag holds the parsed contents of a `tomv:"key,default=...,required"` tag
+type fieldTag struct {
+	key        string
+	defaultVal string
+	hasDefault bool
+	required   bool
+}
+
+// parseFieldTag parses a field tag, default= consumes any following unrecognized options so defaults may contain commas
+func parseFieldTag(field reflect.StructField) (fieldTag, bool) {
+	tag, hasTag := field.Tag.Lookup("tomv")
+	if tag == "-" {
+		return fieldTag{}, false
+	}
+
+	parts := strings.Split(tag, ",")
+	result := fieldTag{key: parts[0]}
+	if !hasTag || result.key == "" {
+		result.key = strings.ToLower(field.Name)
+	}
+
+	inDefault := false
+	for _, part := range parts[1:] {
+		switch {
+		case part == "required":
+			result.required = true
+			inDefault = false
+		case strings.HasPrefix(part, "default="):
+			result.defaultVal = strings.TrimPrefix(part, "default=")
+			result.hasDefault = true
+			inDefault = true
+		case inDefault:
+			result.defaultVal += "," + part
+		}
+	}
+	return result, true
+}
+
+// bindStruct binds each exported field of v, keys are joined onto prefix and field paths onto path
+func (b *binder) bindStruct(v reflect.Value, prefix, path string, lookup lookupFunc) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, ok := parseFieldTag(field)
+		if !ok {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		fieldPath := joinPath(path, field.Name)
+
+		// Embedded structs without a tag share the parent's keys
+		if field.Anonymous && field.Tag.Get("tomv") == "" && field.Type.Kind() == reflect.Struct {
+			b.bindStruct(fieldValue, prefix, path, lookup)
+			continue
+		}
+
+		key := joinPath(prefix, tag.key)
+
+		// Nested structs are bound field by field so each key gets its own lookup and defaults
+		if isNestedStruct(field.Type) {
+			b.bindStruct(fieldValue, key, fieldPath, lookup)
+			continue
+		}
+
+		raw, err := lookup(key)
+		if err != nil {
+			switch {
+			case !errors.Is(err, ErrNotFound):
+				b.fail(fieldPath, key, err)
+				continue
+			case tag.hasDefault:
+				raw = tag.defaultVal
+			case tag.required:
+				b.fail(fieldPath, key, newError(ErrNotFound, "required variable \"%s\" not found", key))
+				continue
+			default:
+				continue // Optional field, keep its zero value
+			}
+		}
+
+		if err := b.decode(fieldValue, raw, key, fieldPath); err != nil {
+			b.fail(fieldPath, key, err)
+		}
+	}
+}
+
+// decode converts a raw TOML value into v
+func (b *binder) decode(v reflect.Value, raw interface{}, key, path string) error {
+	if raw == nil {
+		return nil
+	}
+
+	// Values that already have the right type (strings, time.Time, maps, ...) are assigned directly,
+	// tables and arrays are copied so callers can't modify the cached snapshot
+	rawValue := reflect.ValueOf(raw)
+	if rawValue.Type().AssignableTo(v.Type()) {
+		v.Set(reflect.ValueOf(deepCopyValue(raw)))
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := b.decode(elem.Elem(), raw, key, path); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		unmarshaler := v.Addr().Interface().(encoding.TextUnmarshaler)
+		if err := unmarshaler.UnmarshalText([]byte(formatValue(raw))); err != nil {
+			return newMismatch("variable \"%s\" could not be decoded into "+v.Type().String()+": %s", key, err.Error())
+		}
+		return nil
+	}
+
+	if v.Type() == durationType {
+		switch r := raw.(type) {
+		case string:
+			result, err := time.ParseDuration(r)
+			if err != nil {
+				return newMismatch("variable \"%s\" is not a valid duration: %s", key, r)
+			}
+			v.SetInt(int64(result))
+			return nil
+		case int64:
+			v.SetInt(r)
+			return nil
+		}
+		return newMismatch("variable \"%s\" is not a valid duration: %s", key, raw)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(formatValue(raw))
+
+	case reflect.Bool:
+		switch r := raw.(type) {
+		case bool:
+			v.SetBool(r)
+		case int64:
+			// {{ENV.DEBUG}} with DEBUG=1 resolves to an integer
+			if r != 0 && r != 1 {
+				return newMismatch("variable \"%s\" is not a valid boolean: %s", key, raw)
+			}
+			v.SetBool(r == 1)
+		case string:
+			result, ok := parseBool(r)
+			if !ok {
+				return newMismatch("variable \"%s\" is not a valid boolean: %s", key, r)
+			}
+			v.SetBool(result)
+		default:
+			return newMismatch("variable \"%s\" is not a valid boolean: %s", key, raw)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var result int64
+		switch r := raw.(type) {
+		case int64:
+			result = r
+		case float64:
+			if r != math.Trunc(r) || r < math.MinInt64 || r >= math.MaxInt64 {
+				return newMismatch("variable \"%s\" is not a valid integer: %s", key, raw)
+			}
+			result = int64(r)
+		case string:
+			parsed, err := strconv.ParseInt(strings.TrimSpace(r), 10, 64)
+			if err != nil {
+				return newMismatch("variable \"%s\" is not a valid integer: %s", key, r)
+			}
+			result = parsed
+		default:
+			return newMismatch("variable \"%s\" is not a valid integer: %s", key, raw)
+		}
+		if v.OverflowInt(result) {
+			return newMismatch("variable \"%s\" overflows "+v.Type().String()+": %s", key, result)
+		}
+		v.SetInt(result)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var result uint64
+		switch r := raw.(type) {
+		case int64:
+			if r < 0 {
+				return newMismatch("variable \"%s\" is negative: %s", key, r)
+			}
+			result = uint64(r)
+		case string:
+			parsed, err := strconv.ParseUint(strings.TrimSpace(r), 10, 64)
+			if err != nil {
+				return newMismatch("variable \"%s\" is not a valid unsigned integer: %s", key, r)
+			}
+			result = parsed
+		default:
+			return newMismatch("variable \"%s\" is not a valid unsigned integer: %s", key, raw)
+		}
+		if v.OverflowUint(result) {
+			return newMismatch("variable \"%s\" overflows "+v.Type().String()+": %s", key, result)
+		}
+		v.SetUint(result)
+
+	case reflect.Float32, reflect.Float64:
+		switch r := raw.(type) {
+		case float64:
+			v.SetFloat(r)
+		case int64:
+			v.SetFloat(float64(r))
+		case string:
+			result, err := strconv.ParseFloat(strings.TrimSpace(r), 64)
+			if err != nil {
+				return newMismatch("variable \"%s\" is not a valid float: %s", key, r)
+			}
+			v.SetFloat(result)
+		default:
+			return newMismatch("variable \"%s\" is not a valid float: %s", key, raw)
+		}
+
+	case reflect.Slice:
+		var items []interface{}
+		switch r := raw.(type) {
+		case []interface{}:
+			items = r
+		case []map[string]interface{}:
+			for _, item := range r {
+				items = append(items, item)
+			}
+		case string:
+			// Comma-separated strings, same as GetStringSlice
+			for _, item := range splitList(r) {
+				items = append(items, item)
+			}
+		default:
+			return newMismatch("variable \"%s\" is not a valid list: %s", key, raw)
+		}
+
+		result := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			itemKey := fmt.Sprintf("%s.%d", key, i)
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if err := b.decode(result.Index(i), item, itemKey, itemPath); err != nil {
+				return err
+			}
+		}
+		v.Set(result)
+
+	case reflect.Map:
+		table, ok := raw.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return newError(ErrTypeMismatch, "variable \"%s\" cannot be decoded into %s", key, v.Type())
+		}
+
+		result := reflect.MakeMapWithSize(v.Type(), len(table))
+		for name, item := range table {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := b.decode(elem, item, joinPath(key, name), fmt.Sprintf("%s[%q]", path, name)); err != nil {
+				return err
+			}
+			result.SetMapIndex(reflect.ValueOf(name).Convert(v.Type().Key()), elem)
+		}
+		v.Set(result)
+
+	case reflect.Struct:
+		// Tables inside lists and maps are bound against the table itself
+		table, ok := raw.(map[string]interface{})
+		if !ok {
+			return newError(ErrTypeMismatch, "variable \"%s\" cannot be decoded into %s", key, v.Type())
+		}
+		b.bindStruct(v, key, path, func(fieldKey string) (interface{}, error) {
+			relative := strings.TrimPrefix(fieldKey, key+".")
+			if value, found := resolveKey(table, relative); found {
+				return value, nil
+			}
+			return nil, &NotFoundError{Key: fieldKey}
+		})
+
+	default:
+		return newError(ErrTypeMismatch, "variable \"%s\" cannot be decoded into unsupported type %s", key, v.Type())
+	}
+
+	return nil
+}
+
+// fail records a field error
+func (b *binder) fail(path, key string, err error) {
+	b.errors = append(b.errors, &FieldError{Field: path, Key: key, Err: err})
+}
+
+// isNestedStruct reports whether t is a plain struct that should be bound key by key
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct &&
+		t != reflect.TypeOf(time.Time{}) &&
+		!reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// joinPath joins dotted key or field path segments, skipping an empty prefix
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// Bind fills a struct from the default Config using `tomv` field tags
+func Bind(target interface{}) error {
+	return defaultConfig.Bind(target)
+}
+
+// Unmarshal fills a struct from the default Config, with tag keys relative to prefix
+func Unmarshal(prefix string, target interface{}) error {
+	return defaultConfig.Unmarshal(prefix, target)
+}
@@ -1,104 +1,259 @@
 package tomv
 
 import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
 	"os"
-	"sync"
 	"time"
 )
 
 type cacheEntry struct {
-	value     string
-	timestamp time.Time
-	err       error
+	value    interface{}
+	err      error
+	masked   interface{} // Value with its secrets replaced, shown in errors instead of value
+	position Position    // Where the value is defined
 }
 
-var (
-	cache      = make(map[string]cacheEntry)
-	cacheMutex = sync.RWMutex{}
-	fileCache  = make(map[string]time.Time) // Track file modification times
-)
+// describe adds where an entry is defined to an error about its value, showing the value masked
+func (e cacheEntry) describe(err error) error {
+	if err == nil {
+		return nil
+	}
+	return atPosition(maskMismatch(err, e.masked), e.position)
+}
+
+// snapshot is one fully resolved load of the file set, replaced wholesale when files change
+type snapshot struct {
+	generation       uint64
+	files            []FileData
+	err              error                 // Discovery or resolution error shared by every key
+	diagnostics      []*Diagnostic         // Files left out because they failed to parse or overlay another environment, and scan warnings
+	explicitPrefixes bool                  // Only app:server.port selects a file by prefix
+	fileStamps       map[string]fileStamp  // Fingerprint of the file set this snapshot was built from
+	values           map[string]cacheEntry // Per-key lookup results, guarded by Config.mutex
+}
+
+// fileStamp identifies one version of a file
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+	hash    uint64 // Content hash, zero unless WithContentHash is set
+}
+
+// lookup searches the snapshot for a key
+func (s *snapshot) lookup(key string) cacheEntry {
+	if s.err != nil {
+		return cacheEntry{err: s.err}
+	}
+	fileData, localKey, err := locateKey(s.files, key, s.explicitPrefixes)
+	if err != nil {
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) {
+			notFound.Diagnostics = leftOut(s.diagnostics)
+		}
+		var prefixErr *PrefixNotFoundError
+		if errors.As(err, &prefixErr) {
+			prefixErr.Diagnostics = leftOut(s.diagnostics)
+		}
+		return cacheEntry{err: err}
+	}
+	value, _ := resolveKey(fileData.Resolved, localKey)
+	position, _ := fileData.position(localKey)
+	return cacheEntry{value: value, masked: fileData.masked(localKey, value), position: position}
+}
 
-// getValueFromCache retrieves a value with smart file monitoring
-func getValueFromCache(key string) (string, error) {
-	cacheMutex.RLock()
+// getEntry retrieves a value with smart file monitoring
+//
+// Cache hits only take a read lock and, unless the revalidation interval has
+// elapsed, never touch the filesystem.
+func (c *Config) getEntry(key string) cacheEntry {
+	c.mutex.RLock()
 
 	// Check if we have a cached value and if files haven't changed
-	if entry, exists := cache[key]; exists && !filesChanged() {
-		cacheMutex.RUnlock()
-		return entry.value, entry.err
+	if c.current != nil && c.isFresh() {
+		if entry, exists := c.current.values[key]; exists {
+			c.mutex.RUnlock()
+			return entry
+		}
 	}
 
-	cacheMutex.RUnlock()
+	c.mutex.RUnlock()
 
 	// Files changed or no cache - need to reload
-	cacheMutex.Lock()
-	defer cacheMutex.Unlock()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	// Double-check after acquiring write lock
-	if entry, exists := cache[key]; exists && !filesChanged() {
-		return entry.value, entry.err
+	snap := c.currentSnapshot()
+	entry, exists := snap.values[key]
+	if !exists {
+		entry = snap.lookup(key)
+		snap.values[key] = entry
 	}
+	return entry
+}
 
-	// Update file timestamps and reload value
-	updateFileTimestamps()
-	value, err := findValueInFiles(key)
+// loadSnapshot returns the current snapshot, revalidating it first if needed
+func (c *Config) loadSnapshot() *snapshot {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.currentSnapshot()
+}
 
-	// Cache the result
-	cache[key] = cacheEntry{
-		value:     value,
-		timestamp: time.Now(),
-		err:       err,
+// currentSnapshot revalidates and returns the current snapshot, caller must hold the write lock
+func (c *Config) currentSnapshot() *snapshot {
+	if c.current == nil || !c.isFresh() {
+		if c.current == nil || c.stale || c.filesChanged() {
+			c.reload()
+		}
+		c.lastCheck = time.Now()
+		c.stale = false
 	}
+	return c.current
+}
 
-	return value, err
+// isFresh reports whether the current snapshot can be used without checking files, caller must hold a lock
+func (c *Config) isFresh() bool {
+	switch {
+	case c.stale:
+		return false
+	case c.watching:
+		return true // The watcher reloads on change
+	case c.revalidateInterval < 0:
+		return true // Only Invalidate refreshes
+	case c.revalidateInterval > 0:
+		return time.Since(c.lastCheck) < c.revalidateInterval
+	default:
+		return !c.filesChanged()
+	}
 }
 
-// filesChanged checks if any TOML files have been modified since last check
-func filesChanged() bool {
-	files, err := findTOMLFiles()
+// filesChanged checks if the TOML file set differs from the one the current snapshot was built from
+//
+// Added, deleted and renamed files change the set itself; edits change a
+// file's size, modification time or, with WithContentHash, its content.
+func (c *Config) filesChanged() bool {
+	files, err := c.findTOMLFiles()
 	if err != nil {
 		return true // Assume changed if we can't check
 	}
 
+	if len(files) != len(c.current.fileStamps) {
+		return true
+	}
 	for _, file := range files {
-		stat, err := os.Stat(file)
-		if err != nil {
-			return true // File might have been deleted
-		}
-
-		lastModified := stat.ModTime()
-		if cachedTime, exists := fileCache[file]; !exists || lastModified.After(cachedTime) {
+		cached, exists := c.current.fileStamps[file]
+		if !exists {
 			return true
 		}
+		stamp, err := c.stampFile(file)
+		if err != nil || stamp != cached {
+			return true // File might have been deleted
+		}
 	}
 
 	return false
 }
 
-// updateFileTimestamps updates our record of file modification times
-func updateFileTimestamps() {
-	files, err := findTOMLFiles()
+// stampFile fingerprints a file from its metadata and, if enabled, its content
+func (c *Config) stampFile(file string) (fileStamp, error) {
+	stat, err := os.Stat(file)
 	if err != nil {
-		return
+		return fileStamp{}, err
 	}
+	stamp := fileStamp{size: stat.Size(), modTime: stat.ModTime()}
 
-	// Clear cache when files change
-	cache = make(map[string]cacheEntry)
+	if c.contentHash {
+		f, err := os.Open(file)
+		if err != nil {
+			return fileStamp{}, err
+		}
+		defer f.Close()
 
-	// Update file timestamps
-	for _, file := range files {
-		stat, err := os.Stat(file)
-		if err == nil {
-			fileCache[file] = stat.ModTime()
+		h := fnv.New64a()
+		if _, err := io.Copy(h, f); err != nil {
+			return fileStamp{}, err
 		}
+		stamp.hash = h.Sum64()
 	}
+	return stamp, nil
+}
+
+// reload discovers, loads and resolves the file set into a new snapshot
+func (c *Config) reload() {
+	c.generation++
+	snap := &snapshot{
+		generation:       c.generation,
+		explicitPrefixes: c.explicitPrefixes,
+		fileStamps:       make(map[string]fileStamp),
+		values:           make(map[string]cacheEntry),
+	}
+
+	files, err := c.findTOMLFiles()
+	if err == nil {
+		// Fingerprint before loading so edits made during the load trigger another reload
+		for _, file := range files {
+			if stamp, err := c.stampFile(file); err == nil {
+				snap.fileStamps[file] = stamp
+			}
+		}
+		snap.files, snap.diagnostics, snap.err = loadAllTOMLFiles(files, c.loadOptions())
+	} else {
+		snap.err = fmt.Errorf("failed to discover TOML files: %w", err)
+	}
+
+	c.current = snap
+}
+
+// loadOptions collects the settings loadAllTOMLFiles needs
+func (c *Config) loadOptions() loadOptions {
+	root, _ := c.findProjectRoot()
+	return loadOptions{
+		environments:     c.activeEnvironments(),
+		secretKeys:       c.secretKeys,
+		strict:           c.strictParsing,
+		root:             root,
+		prefixStrategy:   c.prefixStrategy,
+		explicitPrefixes: c.explicitPrefixes,
+	}
+}
+
+// Diagnostics lists the files left out of the last load, because they failed to parse or overlay an unselected environment
+//
+// Files that loaded but whose key positions could not be scanned are listed
+// too, with the DiagnosticPositions kind.
+func (c *Config) Diagnostics() []*Diagnostic {
+	return c.loadSnapshot().diagnostics
+}
+
+// Invalidate marks the cached configuration stale so the next read reloads every file
+func (c *Config) Invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.stale = true
 }
 
 // clearCache clears all cached values (useful for testing)
+func (c *Config) clearCache() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.current = nil
+}
+
+// clearCache clears the default Config's cached values (useful for testing)
 func clearCache() {
-	cacheMutex.Lock()
-	defer cacheMutex.Unlock()
+	defaultConfig.clearCache()
+}
+
+// Invalidate marks the default Config's cached configuration stale
+func Invalidate() {
+	defaultConfig.Invalidate()
+}
 
-	cache = make(map[string]cacheEntry)
-	fileCache = make(map[string]time.Time)
+// Diagnostics lists the default Config's files left out of the last load
+func Diagnostics() []*Diagnostic {
+	return defaultConfig.Diagnostics()
 }
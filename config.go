@@ -0,0 +1,181 @@
+package tomv
+
+import (
+	"sync"
+	"time"
+)
+
+// Config is an isolated tomv instance with its own root directory, file set and cache
+type Config struct {
+	root        string   // Search root; empty means discover the project root from the working directory
+	files       []string // Explicit files to load instead of walking the tree
+	searchPaths []string // Directories to walk, relative to root
+	include     []string // Glob patterns a file must match to be loaded
+	exclude     []string // Glob patterns that skip files and directories
+
+	revalidateInterval time.Duration  // How long a snapshot is trusted before files are checked again
+	pollInterval       time.Duration  // Forces the watcher to poll at this interval instead of using native notifications
+	contentHash        bool           // Hash file contents when checking for changes
+	environments       []string       // Overlay layers to merge, overrides TOMV_ENV
+	secretKeys         []string       // Key patterns whose values are secret
+	schema             *Schema        // Checked by Validate, overrides tomv.schema.toml
+	strictParsing      bool           // Fail every read when a file can't be parsed
+	prefixStrategy     PrefixStrategy // How file paths become prefixes
+	explicitPrefixes   bool           // Only app:server.port selects a file by prefix
+
+	mutex      sync.RWMutex
+	current    *snapshot // Resolved configuration, nil until first read
+	generation uint64    // Incremented on every reload
+	lastCheck  time.Time // When files were last checked for changes
+	stale      bool      // Set by Invalidate to force a reload
+	watching   bool      // Set while a watcher keeps the snapshot current
+
+	watcher watchState
+}
+
+// Option configures a Config created by New
+type Option func(*Config)
+
+// WithRoot sets the directory searched for TOML files, skipping project root discovery
+func WithRoot(dir string) Option {
+	return func(c *Config) {
+		c.root = dir
+	}
+}
+
+// WithFiles loads exactly these TOML files, relative paths are resolved against the root
+func WithFiles(files ...string) Option {
+	return func(c *Config) {
+		c.files = append(c.files, files...)
+	}
+}
+
+// WithSearchPaths limits discovery to these directories, relative paths are resolved against the root
+func WithSearchPaths(dirs ...string) Option {
+	return func(c *Config) {
+		c.searchPaths = append(c.searchPaths, dirs...)
+	}
+}
+
+// WithInclude only loads discovered files matching at least one glob pattern (e.g. "config/**/*.toml")
+func WithInclude(patterns ...string) Option {
+	return func(c *Config) {
+		c.include = append(c.include, patterns...)
+	}
+}
+
+// WithExclude skips discovered files and directories matching any glob pattern (e.g. "testdata", "Cargo.toml")
+func WithExclude(patterns ...string) Option {
+	return func(c *Config) {
+		c.exclude = append(c.exclude, patterns...)
+	}
+}
+
+// WithRevalidateInterval trusts cached values for d before checking files for changes
+//
+// Zero (the default) checks files on every read. A negative interval never
+// checks automatically, leaving refreshes to Invalidate or a watcher.
+func WithRevalidateInterval(d time.Duration) Option {
+	return func(c *Config) {
+		c.revalidateInterval = d
+	}
+}
+
+// WithPollInterval makes Watch poll for changes every d instead of using native file notifications
+func WithPollInterval(d time.Duration) Option {
+	return func(c *Config) {
+		c.pollInterval = d
+	}
+}
+
+// WithContentHash also compares file contents when checking for changes
+//
+// This catches edits that keep the same size within the filesystem's
+// timestamp granularity, at the cost of reading every file on each check.
+func WithContentHash() Option {
+	return func(c *Config) {
+		c.contentHash = true
+	}
+}
+
+// WithEnvironment merges <name>.<env>.toml overlays over <name>.toml, later environments win
+//
+// Without this option the comma-separated TOMV_ENV variable selects the
+// environments, e.g. TOMV_ENV=production,local.
+func WithEnvironment(envs ...string) Option {
+	return func(c *Config) {
+		c.environments = append(c.environments, envs...)
+	}
+}
+
+// WithSecretKeys marks keys matching glob patterns (e.g. "password", "db.*") as secret
+//
+// Secret values are masked in errors, change events and dumps. Keys can also
+// be marked in the file itself with a trailing # tomv:secret comment.
+func WithSecretKeys(patterns ...string) Option {
+	return func(c *Config) {
+		c.secretKeys = append(c.secretKeys, patterns...)
+	}
+}
+
+// WithSchema makes Validate check the configuration against schema instead of tomv.schema.toml
+func WithSchema(schema *Schema) Option {
+	return func(c *Config) {
+		c.schema = schema
+	}
+}
+
+// WithStrictParsing fails every read while any TOML file can't be parsed
+//
+// By default such files are skipped, recorded in Diagnostics and listed in
+// not-found errors, since their keys would otherwise vanish silently.
+func WithStrictParsing() Option {
+	return func(c *Config) {
+		c.strictParsing = true
+	}
+}
+
+// WithPrefixStrategy chooses how file paths become the prefixes used by explicit keys
+//
+// PrefixRelativePath addresses services/api/app.toml as
+// services/api/app.server.port, so same-named files in different
+// directories stop colliding.
+func WithPrefixStrategy(strategy PrefixStrategy) Option {
+	return func(c *Config) {
+		c.prefixStrategy = strategy
+	}
+}
+
+// WithExplicitPrefixes stops guessing that a key's first segment names a file
+//
+// Keys and references then select a file only with app:server.port, so
+// server.port finds [server] in app.toml even when server.toml exists. A
+// colon in the first segment always reads as a file prefix: a top-level key
+// such as "host:port" can only be reached as app:host:port.
+func WithExplicitPrefixes() Option {
+	return func(c *Config) {
+		c.explicitPrefixes = true
+	}
+}
+
+// New creates a Config with its own file set and cache
+func New(opts ...Option) *Config {
+	c := &Config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// defaultConfig backs the package-level functions (Get, GetInt, ...)
+var defaultConfig = New()
+
+// Default returns the Config used by the package-level functions
+func Default() *Config {
+	return defaultConfig
+}
+
+// SetDefault replaces the Config used by the package-level functions, call it before any reads
+func SetDefault(c *Config) {
+	defaultConfig = c
+}
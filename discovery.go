@@ -1,16 +1,23 @@
 package tomv
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/BurntSushi/toml"
 )
 
-// findProjectRoot discovers the project root by looking for go.mod or .git
-func findProjectRoot() (string, error) {
+// findProjectRoot returns the configured root, or discovers the project root by looking for go.mod or .git
+func (c *Config) findProjectRoot() (string, error) {
+	if c.root != "" {
+		return filepath.Abs(c.root)
+	}
+
 	currentDir, err := os.Getwd()
 	if err != nil {
 		return "", fmt.Errorf("failed to get current directory: %v", err)
@@ -41,90 +48,240 @@ func findProjectRoot() (string, error) {
 	return currentDir, nil
 }
 
-// findTOMLFiles discovers TOML files in the project directory
-func findTOMLFiles() ([]string, error) {
-	projectRoot, err := findProjectRoot()
+// findTOMLFiles discovers TOML files in the project directory, honoring the configured
+// explicit files, search paths, include/exclude patterns and .tomvignore
+func (c *Config) findTOMLFiles() ([]string, error) {
+	projectRoot, err := c.findProjectRoot()
 	if err != nil {
 		return nil, err
 	}
 
+	// Explicit files bypass discovery entirely
+	if len(c.files) > 0 {
+		var tomlFiles []string
+		for _, file := range c.files {
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(projectRoot, file)
+			}
+			if _, err := os.Stat(file); err != nil {
+				return nil, fmt.Errorf("configured TOML file not found: %w", err)
+			}
+			tomlFiles = append(tomlFiles, file)
+		}
+		return tomlFiles, nil
+	}
+
 	var tomlFiles []string
+	seen := make(map[string]bool)
 
-	err = filepath.Walk(projectRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	err = c.walkSearchPaths(projectRoot, func(path, relPath string, info os.FileInfo) {
+		// Check for .toml extension, the schema describes the configuration rather than being part of it
+		if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ".toml") && info.Name() != schemaFileName {
+			if len(c.include) > 0 && !matchesAny(c.include, relPath, false) {
+				return
+			}
+			if !seen[path] {
+				seen[path] = true
+				tomlFiles = append(tomlFiles, path)
+			}
 		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tomlFiles, nil
+}
 
-		// Skip hidden directories and files
-		if strings.HasPrefix(info.Name(), ".") {
-			if info.IsDir() {
-				return filepath.SkipDir
+// walkSearchPaths visits every file and directory of the search paths, skipping hidden and excluded ones
+func (c *Config) walkSearchPaths(projectRoot string, visit func(path, relPath string, info os.FileInfo)) error {
+	ignored, err := readIgnoreFile(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", ignoreFileName, err)
+	}
+	exclude := append(append([]string{}, c.exclude...), ignored...)
+
+	searchPaths := []string{projectRoot}
+	if len(c.searchPaths) > 0 {
+		searchPaths = nil
+		for _, dir := range c.searchPaths {
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(projectRoot, dir)
 			}
-			return nil
+			searchPaths = append(searchPaths, dir)
 		}
+	}
 
-		// Check for .toml extension
-		if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ".toml") {
-			tomlFiles = append(tomlFiles, path)
-		}
+	for _, searchPath := range searchPaths {
+		err = filepath.Walk(searchPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
 
-		return nil
-	})
+			// Skip hidden directories and files
+			if strings.HasPrefix(info.Name(), ".") && path != searchPath {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			relPath, err := filepath.Rel(projectRoot, path)
+			if err != nil {
+				relPath = path
+			}
 
-	return tomlFiles, err
+			// Skip excluded directories and files
+			if path != searchPath && matchesAny(exclude, relPath, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			visit(path, relPath, info)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// loadTOMLFile loads and parses a TOML file into a nested map
+// loadTOMLFile loads and parses a TOML file into a nested map, failures are returned as a *Diagnostic
 func loadTOMLFile(filename string) (map[string]interface{}, error) {
 	var config map[string]interface{}
 
 	_, err := toml.DecodeFile(filename, &config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse TOML file %s: %v", filename, err)
+		diagnostic := &Diagnostic{Position: Position{File: filename}, Kind: DiagnosticParse, Message: err.Error(), Err: err}
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			diagnostic.Line, diagnostic.Column = parseErr.Position.Line, parseErr.Position.Col
+			diagnostic.Message = parseErr.Message
+		}
+		return nil, diagnostic
 	}
 
 	return config, nil
 }
 
 // resolveKey looks up a value in the nested TOML structure using dot notation
-func resolveKey(data map[string]interface{}, key string) (string, bool) {
-	parts := strings.Split(key, ".")
-
-	current := data
-	for i, part := range parts {
-		if i == len(parts)-1 {
-			// Last part - get the actual value
-			if value, exists := current[part]; exists {
-				// Convert to string
-				return fmt.Sprintf("%v", value), true
-			}
-			return "", false
-		} else {
-			// Intermediate part - must be a map
-			if nextMap, exists := current[part]; exists {
-				if typedMap, ok := nextMap.(map[string]interface{}); ok {
-					current = typedMap
-				} else {
-					// Path exists but is not a map
-					return "", false
-				}
-			} else {
+// Numeric segments index into arrays: upstreams.0.url
+func resolveKey(data map[string]interface{}, key string) (interface{}, bool) {
+	var current interface{} = data
+	for _, part := range strings.Split(key, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, exists := node[part]
+			if !exists {
 				// Path doesn't exist
-				return "", false
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, ok := arrayIndex(part, len(node))
+			if !ok {
+				return nil, false
+			}
+			current = node[index]
+		case []map[string]interface{}:
+			index, ok := arrayIndex(part, len(node))
+			if !ok {
+				return nil, false
 			}
+			current = node[index]
+		default:
+			// Path exists but is not a table or array
+			return nil, false
 		}
 	}
 
-	return "", false
+	return current, true
+}
+
+// arrayIndex parses a key segment as an index into an array of the given length
+func arrayIndex(part string, length int) (int, bool) {
+	index, err := strconv.Atoi(part)
+	if err != nil || index < 0 || index >= length {
+		return 0, false
+	}
+	return index, true
 }
 
 // FileData represents a loaded TOML file with its resolved data
 type FileData struct {
-	Path          string
-	Prefix        string
-	Data          map[string]interface{}
-	Resolved      map[string]interface{}
-	resolvedValue string // Used for storing resolved value during search
+	Path      string
+	Prefix    string
+	Data      map[string]interface{}
+	Resolved  map[string]interface{}
+	Layers    []string            // Overlay files merged over Path, lowest precedence first
+	secrets   map[string]bool     // Keys holding secret values
+	positions map[string]Position // Where keys and tables are defined
+}
+
+// position returns where a key, or the closest table or key containing it, is defined
+func (f *FileData) position(key string) (Position, bool) {
+	for {
+		if pos, exists := f.positions[key]; exists {
+			return pos, true
+		}
+		dot := strings.LastIndex(key, ".")
+		if dot < 0 {
+			return Position{}, false
+		}
+		key = key[:dot]
+	}
+}
+
+// masked returns value, read from key, with every secret it holds replaced by ****
+//
+// Tables are copied with only their secret keys replaced, anything else
+// holding a secret is replaced whole.
+func (f *FileData) masked(key string, value interface{}) interface{} {
+	if f.isSecret(key) {
+		return redactedValue
+	}
+	if !coversSecret(f.secrets, key) {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for name, item := range v {
+			result[name] = f.masked(joinPath(key, name), item)
+		}
+		return result
+	case []map[string]interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = f.masked(key+"."+strconv.Itoa(i), item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = f.masked(key+"."+strconv.Itoa(i), item)
+		}
+		return result
+	default:
+		return redactedValue
+	}
+}
+
+// isSecret reports whether a key, or a table containing it, holds a secret value
+func (f *FileData) isSecret(key string) bool {
+	for {
+		if f.secrets[key] {
+			return true
+		}
+		dot := strings.LastIndex(key, ".")
+		if dot < 0 {
+			return false
+		}
+		key = key[:dot]
+	}
 }
 
 // extractFilePrefix extracts filename prefix from path (app.toml -> app)
@@ -134,44 +291,268 @@ func extractFilePrefix(filePath string) string {
 	return name
 }
 
-// loadAllTOMLFiles loads all TOML files with namespaced architecture
-func loadAllTOMLFiles() ([]FileData, error) {
-	files, err := findTOMLFiles()
-	if err != nil {
-		return nil, fmt.Errorf("failed to discover TOML files: %v", err)
+// PrefixStrategy decides how a file's path becomes the prefix addressing its keys
+//
+// A file can always choose its own prefix with a header:
+//
+//	[tomv]
+//	namespace = "api"
+type PrefixStrategy int
+
+const (
+	// PrefixBaseName uses the file name: services/api/app.toml -> app (the default)
+	PrefixBaseName PrefixStrategy = iota
+	// PrefixRelativePath uses the slash-separated path from the root: services/api/app.toml -> services/api/app
+	PrefixRelativePath
+)
+
+// namespaceTable is the reserved table holding a file's [tomv] header
+const namespaceTable = "tomv"
+
+// loadOptions carries the Config settings that change how files are loaded and resolved
+type loadOptions struct {
+	environments     []string       // Overlay layers to merge
+	secretKeys       []string       // Key patterns whose values are secret
+	strict           bool           // Fail on files that can't be parsed instead of skipping them
+	root             string         // Base of relative path prefixes
+	prefixStrategy   PrefixStrategy // How file paths become prefixes
+	explicitPrefixes bool           // Only app:server.port selects a file by prefix
+}
+
+// filePrefix returns the prefix of a file without a namespace header
+func (o loadOptions) filePrefix(file string) string {
+	if o.prefixStrategy == PrefixRelativePath && o.root != "" {
+		if rel, err := filepath.Rel(o.root, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+		}
+	}
+	return extractFilePrefix(file)
+}
+
+// checkPrefix rejects a computed prefix that keys could not address
+//
+// Dotted base names of standalone files are accepted as before, their keys
+// stay reachable without the prefix.
+func (o loadOptions) checkPrefix(file, prefix string) error {
+	invalid := explicitSeparator
+	if o.prefixStrategy == PrefixRelativePath {
+		invalid += "."
+	}
+	if strings.ContainsAny(prefix, invalid) {
+		return fmt.Errorf("file prefix \"%s\" of %s can't be addressed because it contains one of %q\n\nRename the path, or name the file with a header:\n[tomv]\nnamespace = \"...\"", prefix, file, invalid)
+	}
+	return nil
+}
+
+// takeNamespace removes the [tomv] namespace header from a file's data and returns it
+func takeNamespace(file string, data map[string]interface{}) (string, bool, error) {
+	header, ok := data[namespaceTable].(map[string]interface{})
+	if !ok {
+		return "", false, nil
+	}
+	value, exists := header["namespace"]
+	if !exists {
+		return "", false, nil
+	}
+	delete(header, "namespace")
+	if len(header) == 0 {
+		delete(data, namespaceTable)
+	}
+
+	namespace, ok := value.(string)
+	if !ok || namespace == "" || strings.ContainsAny(namespace, ".:{}") {
+		return "", false, fmt.Errorf("invalid [tomv] namespace %v in %s: it must be a non-empty string without dots, colons or braces", value, file)
 	}
+	return namespace, true, nil
+}
 
+// loadAllTOMLFiles loads the discovered TOML files with namespaced architecture
+//
+// Overlays of the selected environments are merged into their base file
+// before variables are resolved, so references see the layered values. Keys
+// annotated with # tomv:secret or matching secretKeys are marked secret.
+// Files that fail to parse are skipped and reported as diagnostics, or fail
+// the load in strict mode.
+func loadAllTOMLFiles(files []string, opts loadOptions) ([]FileData, []*Diagnostic, error) {
 	var fileDataList []FileData
+	var diagnostics []*Diagnostic
 	namespacedData := make(map[string]interface{})
+	marked := make(map[string]bool)
+	prefixFiles := make(map[string][]string)
+	bases, overlays, skipped := groupLayers(files, opts.environments)
+	for _, overlay := range skipped {
+		diagnostics = append(diagnostics, &Diagnostic{
+			Position: Position{File: overlay},
+			Kind:     DiagnosticOverlay,
+			Message:  fmt.Sprintf("overlay for environment \"%s\", which is not selected", layerName(overlay)),
+		})
+	}
+
+	skip := func(err error) {
+		var diagnostic *Diagnostic
+		if errors.As(err, &diagnostic) {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
 
 	// First pass: Load all files and namespace them
-	for _, file := range files {
+	for _, file := range bases {
 		data, err := loadTOMLFile(file)
 		if err != nil {
-			continue // Skip files that can't be loaded
+			skip(err) // Skip files that can't be loaded
+			continue
+		}
+
+		prefix := opts.filePrefix(file)
+		if namespace, ok, err := takeNamespace(file, data); err != nil {
+			return nil, diagnostics, err
+		} else if ok {
+			prefix = namespace
+		} else if err := opts.checkPrefix(file, prefix); err != nil {
+			return nil, diagnostics, err
+		}
+		prefixFiles[prefix] = append(prefixFiles[prefix], file)
+
+		var layers []string
+		for _, overlay := range overlays[file] {
+			overlayData, err := loadTOMLFile(overlay)
+			if err != nil {
+				skip(err)
+				continue
+			}
+			takeNamespace(overlay, overlayData) // The base file names the namespace
+			mergeTables(data, overlayData)
+			layers = append(layers, overlay)
+		}
+
+		// Record where keys are defined, overlays last so they win, and mark annotated secrets
+		positions := make(map[string]Position)
+		for _, path := range append([]string{file}, layers...) {
+			scanned, err := scanKeys(path)
+			if err != nil {
+				// The data loaded, but positions and # tomv:secret markers are missing
+				diagnostics = append(diagnostics, &Diagnostic{
+					Position: Position{File: path},
+					Kind:     DiagnosticPositions,
+					Message:  err.Error(),
+					Err:      err,
+				})
+			}
+			for _, k := range scanned {
+				positions[k.key] = k.pos
+				if k.secret {
+					marked[prefix+"."+k.key] = true
+				}
+			}
 		}
 
-		prefix := extractFilePrefix(file)
 		fileData := FileData{
-			Path:   file,
-			Prefix: prefix,
-			Data:   data,
+			Path:      file,
+			Prefix:    prefix,
+			Data:      data,
+			Layers:    layers,
+			positions: positions,
 		}
 
 		fileDataList = append(fileDataList, fileData)
 
 		// Add to namespaced structure: namespacedData[filePrefix][section][key]
 		namespacedData[prefix] = data
+
+		// Mark secrets by key pattern
+		var keys []string
+		collectKeys(data, "", &keys)
+		for _, key := range keys {
+			if matchesKeyPattern(opts.secretKeys, key) {
+				marked[prefix+"."+key] = true
+			}
+		}
+	}
+	secrets := findSecretKeys(namespacedData, marked, opts.explicitPrefixes)
+
+	// Same-named files would overwrite each other in namespacedData
+	var collisions []error
+	for prefix, paths := range prefixFiles {
+		if len(paths) > 1 {
+			collisions = append(collisions, &PrefixCollisionError{Prefix: prefix, Files: paths})
+		}
+	}
+	if len(collisions) > 0 {
+		sort.Slice(collisions, func(i, j int) bool {
+			return collisions[i].(*PrefixCollisionError).Prefix < collisions[j].(*PrefixCollisionError).Prefix
+		})
+		return nil, diagnostics, errors.Join(collisions...)
+	}
+
+	if opts.strict {
+		var errs []error
+		for _, diagnostic := range diagnostics {
+			if diagnostic.Kind == DiagnosticParse {
+				errs = append(errs, diagnostic)
+			}
+		}
+		if len(errs) > 0 {
+			return nil, diagnostics, errors.Join(errs...)
+		}
 	}
 
 	// Second pass: Resolve variables using namespaced structure
-	resolvedNamespaced, err := resolveVariables(namespacedData)
+	resolvedNamespaced, err := resolveVariables(namespacedData, opts.explicitPrefixes, newSourceLoad(opts.root))
 	if err != nil {
-		return nil, fmt.Errorf("error resolving cross-file variables: %v", err)
+		// The resolver only knows file prefixes, report files by path
+		var refErr *UnresolvedReferenceError
+		if errors.As(err, &refErr) {
+			refErr.Position = prefixPosition(fileDataList, refErr.File, refErr.InKey)
+			refErr.File = prefixPath(fileDataList, refErr.File)
+			refErr.Diagnostics = leftOut(diagnostics)
+		}
+		var conflictErr *ConflictError
+		if errors.As(err, &conflictErr) {
+			conflictErr.Position = prefixPosition(fileDataList, conflictErr.File, conflictErr.InKey)
+			conflictErr.File = prefixPath(fileDataList, conflictErr.File)
+			conflictErr.Positions = make([]Position, len(conflictErr.Files))
+			for i, prefix := range conflictErr.Files {
+				conflictErr.Positions[i] = prefixPosition(fileDataList, prefix, conflictErr.Key)
+				conflictErr.Files[i] = prefixPath(fileDataList, prefix)
+			}
+		}
+		var envErr *MissingEnvError
+		if errors.As(err, &envErr) {
+			envErr.Position = prefixPosition(fileDataList, envErr.File, envErr.InKey)
+			envErr.File = prefixPath(fileDataList, envErr.File)
+		}
+		var filterErr *FilterError
+		if errors.As(err, &filterErr) {
+			filterErr.secret = coversSecret(secrets, filterErr.File+"."+filterErr.InKey)
+			filterErr.Position = prefixPosition(fileDataList, filterErr.File, filterErr.InKey)
+			filterErr.File = prefixPath(fileDataList, filterErr.File)
+		}
+		var sourceErr *SourceError
+		if errors.As(err, &sourceErr) {
+			sourceErr.Position = prefixPosition(fileDataList, sourceErr.File, sourceErr.InKey)
+			sourceErr.File = prefixPath(fileDataList, sourceErr.File)
+		}
+		var cycleErr *CycleError
+		if errors.As(err, &cycleErr) {
+			cycleErr.Positions = make([]Position, len(cycleErr.Path))
+			for i, variable := range cycleErr.Path {
+				prefix, key, _ := strings.Cut(variable, ".")
+				cycleErr.Positions[i] = prefixPosition(fileDataList, prefix, key)
+			}
+		}
+		return nil, diagnostics, fmt.Errorf("error resolving cross-file variables: %w", err)
 	}
 
 	// Third pass: Extract resolved data back to individual files
 	for i := range fileDataList {
+		for qualified := range secrets {
+			if key, ok := strings.CutPrefix(qualified, fileDataList[i].Prefix+"."); ok {
+				if fileDataList[i].secrets == nil {
+					fileDataList[i].secrets = make(map[string]bool)
+				}
+				fileDataList[i].secrets[key] = true
+			}
+		}
 		prefix := fileDataList[i].Prefix
 		if resolvedFileData, exists := resolvedNamespaced[prefix]; exists {
 			if resolvedMap, ok := resolvedFileData.(map[string]interface{}); ok {
@@ -184,22 +565,85 @@ func loadAllTOMLFiles() ([]FileData, error) {
 		}
 	}
 
-	return fileDataList, nil
+	return fileDataList, diagnostics, nil
+}
+
+// prefixPath returns the path of the file loaded under prefix, or prefix itself if there is none
+func prefixPath(fileDataList []FileData, prefix string) string {
+	for _, fileData := range fileDataList {
+		if fileData.Prefix == prefix {
+			return fileData.Path
+		}
+	}
+	return prefix
+}
+
+// prefixPosition returns where a key of the file loaded under prefix is defined
+func prefixPosition(fileDataList []FileData, prefix, key string) Position {
+	for i := range fileDataList {
+		if fileDataList[i].Prefix == prefix {
+			pos, _ := fileDataList[i].position(key)
+			return pos
+		}
+	}
+	return Position{}
+}
+
+// explicitSeparator splits a file prefix from its key: app:server.port
+const explicitSeparator = ":"
+
+// splitExplicitKey splits app:server.port into its file prefix and key
+//
+// Only the first colon counts and only before any dot, so a key whose first
+// segment holds a colon needs its own prefix in front: app:host:port.
+func splitExplicitKey(key string) (string, string, bool) {
+	prefix, rest, ok := strings.Cut(key, explicitSeparator)
+	if !ok || prefix == "" || rest == "" || strings.Contains(prefix, ".") {
+		return "", "", false
+	}
+	return prefix, rest, true
+}
+
+// explicitKey names a key of one file, in the syntax the addressing mode accepts
+func explicitKey(prefix, key string, explicitPrefixes bool) string {
+	if explicitPrefixes {
+		return prefix + explicitSeparator + key
+	}
+	return prefix + "." + key
 }
 
-// findValueInFiles searches for a key with smart lookup and conflict detection
-func findValueInFiles(key string) (string, error) {
-	fileDataList, err := loadAllTOMLFiles()
+// findRawValue searches loaded files for a key and returns its native TOML value
+func findRawValue(fileDataList []FileData, key string, explicitPrefixes bool) (interface{}, error) {
+	fileData, localKey, err := locateKey(fileDataList, key, explicitPrefixes)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	value, _ := resolveKey(fileData.Resolved, localKey)
+	return value, nil
+}
 
+// locateKey finds the file defining a key and the key relative to that file
+//
+// app:server.port always selects a file. Unless explicitPrefixes is set, a
+// first dotted segment naming a file selects it as well: app.server.port.
+func locateKey(fileDataList []FileData, key string, explicitPrefixes bool) (*FileData, string, error) {
 	if len(fileDataList) == 0 {
-		return "", fmt.Errorf("variable \"%s\" not found\n\nNo TOML files found in project", key)
+		return nil, "", &NotFoundError{Key: key}
+	}
+
+	// Explicit file syntax (filename:section.key)
+	if prefix, remainingKey, ok := splitExplicitKey(key); ok {
+		for i := range fileDataList {
+			if fileDataList[i].Prefix == prefix {
+				return findInFile(&fileDataList[i], remainingKey)
+			}
+		}
+		return nil, "", prefixNotFound(fileDataList, key, prefix)
 	}
 
-	// Check if key uses explicit file prefix (filename.section.key)
-	if strings.Contains(key, ".") {
+	// Check if key uses a file prefix (filename.section.key)
+	var invalidPrefix string
+	if strings.Contains(key, ".") && !explicitPrefixes {
 		parts := strings.SplitN(key, ".", 2)
 		if len(parts) == 2 {
 			potentialFilePrefix := parts[0]
@@ -207,41 +651,34 @@ func findValueInFiles(key string) (string, error) {
 
 			// Check if this is actually a file prefix
 			var fileFound bool
-			for _, fileData := range fileDataList {
-				if fileData.Prefix == potentialFilePrefix {
+			for i := range fileDataList {
+				if fileDataList[i].Prefix == potentialFilePrefix {
 					fileFound = true
 					// This is explicit file syntax
-					if value, found := resolveKey(fileData.Resolved, remainingKey); found {
-						return value, nil
-					}
-					// Key not found in specified file
-					return "", fmt.Errorf("variable \"%s\" not found in file %s\n\nAvailable variables in %s:\n%s",
-						remainingKey, fileData.Path, fileData.Path, getFileVariablesList(fileData.Resolved))
+					return findInFile(&fileDataList[i], remainingKey)
 				}
 			}
 
-			// If potentialFilePrefix looks like a file prefix but doesn't exist, and remainingKey contains dots
+			// If potentialFilePrefix looks like a file prefix but doesn't exist, and remainingKey contains dots,
+			// report the prefix unless the regular search below finds a deep key (section.table.key)
 			if !fileFound && strings.Contains(remainingKey, ".") {
-				// This looks like an explicit file syntax with invalid prefix
-				return "", fmt.Errorf("file prefix \"%s\" not found\n\nAvailable file prefixes:\n%s",
-					potentialFilePrefix, getAvailableFilePrefixes(fileDataList))
+				invalidPrefix = potentialFilePrefix
 			}
 			// Otherwise, continue with regular search (might be section.key format)
 		}
 	}
 
 	// Smart lookup: Search for key across all files
-	var foundFiles []FileData
+	var foundFiles []*FileData
 	var searchedFiles []string
 	var allAvailableKeys []string
 
-	for _, fileData := range fileDataList {
+	for i := range fileDataList {
+		fileData := &fileDataList[i]
 		searchedFiles = append(searchedFiles, fileData.Path)
 
-		if value, found := resolveKey(fileData.Resolved, key); found {
+		if _, found := resolveKey(fileData.Resolved, key); found {
 			foundFiles = append(foundFiles, fileData)
-			// Store the actual value for potential return
-			foundFiles[len(foundFiles)-1].resolvedValue = value
 		}
 
 		// Collect available keys for error message
@@ -251,69 +688,86 @@ func findValueInFiles(key string) (string, error) {
 	// Handle results based on how many files contain the key
 	switch len(foundFiles) {
 	case 0:
-		// Variable not found in any file
-		errorMsg := fmt.Sprintf("variable \"%s\" not found\n\nSearched in:", key)
-		for _, file := range searchedFiles {
-			errorMsg += fmt.Sprintf("\n- %s", file)
-		}
-		if len(allAvailableKeys) > 0 {
-			errorMsg += "\n\nAvailable variables:\n" + formatVariablesList(allAvailableKeys)
+		if invalidPrefix != "" {
+			// This looks like an explicit file syntax with invalid prefix
+			return nil, "", prefixNotFound(fileDataList, key, invalidPrefix)
 		}
-		return "", fmt.Errorf("%s", errorMsg)
+
+		// Variable not found in any file
+		return nil, "", &NotFoundError{Key: key, SearchedFiles: searchedFiles, Suggestions: allAvailableKeys}
 
 	case 1:
 		// Variable found in exactly one file - return it
-		return foundFiles[0].resolvedValue, nil
+		return foundFiles[0], key, nil
 
 	default:
 		// Variable found in multiple files - conflict error with helpful message
-		errorMsg := fmt.Sprintf("variable \"%s\" found in multiple files:", key)
-		for _, fileData := range foundFiles {
-			errorMsg += fmt.Sprintf("\n- %s", fileData.Path)
-		}
-		errorMsg += "\n\nUse explicit syntax:"
+		conflict := &ConflictError{Key: key}
 		for _, fileData := range foundFiles {
-			errorMsg += fmt.Sprintf("\n- tomv.Get(\"%s.%s\")", fileData.Prefix, key)
+			pos, _ := fileData.position(key)
+			conflict.Files = append(conflict.Files, fileData.Path)
+			conflict.Positions = append(conflict.Positions, pos)
+			conflict.ExplicitKeys = append(conflict.ExplicitKeys, explicitKey(fileData.Prefix, key, explicitPrefixes))
 		}
-		return "", fmt.Errorf("%s", errorMsg)
+		return nil, "", conflict
+	}
+}
+
+// findInFile looks a key up in one file, listing the file's variables when it is missing
+func findInFile(fileData *FileData, key string) (*FileData, string, error) {
+	if _, found := resolveKey(fileData.Resolved, key); found {
+		return fileData, key, nil
+	}
+	var fileKeys []string
+	collectKeys(fileData.Resolved, "", &fileKeys)
+	return nil, "", &NotFoundError{
+		Key:           key,
+		File:          fileData.Path,
+		SearchedFiles: []string{fileData.Path},
+		Suggestions:   fileKeys,
 	}
 }
 
 // collectKeys recursively collects all available keys from a TOML structure
 func collectKeys(data map[string]interface{}, prefix string, keys *[]string) {
 	for key, value := range data {
-		fullKey := key
-		if prefix != "" {
-			fullKey = prefix + "." + key
-		}
-
-		if subMap, isMap := value.(map[string]interface{}); isMap {
-			collectKeys(subMap, fullKey, keys)
-		} else {
-			*keys = append(*keys, fullKey)
-		}
+		collectValueKeys(value, joinPath(prefix, key), keys)
 	}
 }
 
-// getFileVariablesList returns a formatted list of variables in a specific file
-func getFileVariablesList(data map[string]interface{}) string {
-	var keys []string
-	collectKeys(data, "", &keys)
-
-	if len(keys) == 0 {
-		return "- (no variables found)"
+// collectValueKeys collects the keys of a single value, expanding tables held in arrays by index
+func collectValueKeys(value interface{}, fullKey string, keys *[]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		collectKeys(v, fullKey, keys)
+	case []map[string]interface{}:
+		for i, item := range v {
+			collectKeys(item, fullKey+"."+strconv.Itoa(i), keys)
+		}
+	case []interface{}:
+		// Plain arrays are a single variable, arrays of inline tables expand like [[tables]]
+		if len(v) > 0 {
+			if _, isTable := v[0].(map[string]interface{}); isTable {
+				for i, item := range v {
+					collectValueKeys(item, fullKey+"."+strconv.Itoa(i), keys)
+				}
+				return
+			}
+		}
+		*keys = append(*keys, fullKey)
+	default:
+		*keys = append(*keys, fullKey)
 	}
-
-	return formatVariablesList(keys)
 }
 
-// getAvailableFilePrefixes returns a formatted list of available file prefixes
-func getAvailableFilePrefixes(fileDataList []FileData) string {
-	result := ""
+// prefixNotFound reports key's prefix as missing, listing the prefixes of the loaded files
+func prefixNotFound(fileDataList []FileData, key, prefix string) error {
+	err := &PrefixNotFoundError{Key: key, Prefix: prefix}
 	for _, fileData := range fileDataList {
-		result += fmt.Sprintf("- %s (from %s)\n", fileData.Prefix, fileData.Path)
+		err.Prefixes = append(err.Prefixes, fileData.Prefix)
+		err.Files = append(err.Files, fileData.Path)
 	}
-	return strings.TrimSuffix(result, "\n")
+	return err
 }
 
 // formatVariablesList formats a list of variables for error messages
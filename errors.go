@@ -0,0 +1,492 @@
+package tomv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors returned by the Lookup family, match them with errors.Is
+var (
+	// ErrNotFound is returned when a variable, file prefix or referenced variable does not exist
+	ErrNotFound = errors.New("variable not found")
+
+	// ErrConflict is returned when an unprefixed variable is defined in multiple files
+	ErrConflict = errors.New("variable found in multiple files")
+
+	// ErrTypeMismatch is returned when a value cannot be converted to the requested type
+	ErrTypeMismatch = errors.New("type mismatch")
+
+	// ErrCircularReference is returned when {{variable}} references form a cycle
+	ErrCircularReference = errors.New("circular reference")
+
+	// ErrParse is returned when a TOML file cannot be parsed
+	ErrParse = errors.New("TOML parse error")
+
+	// ErrValidation is returned by Validate when the configuration breaks its schema
+	ErrValidation = errors.New("configuration does not match schema")
+)
+
+// tomvError pairs a human-readable message with the sentinel error it matches
+type tomvError struct {
+	kind error
+	msg  string
+}
+
+func (e *tomvError) Error() string {
+	return e.msg
+}
+
+func (e *tomvError) Unwrap() error {
+	return e.kind
+}
+
+// newError creates an error with a formatted message that matches kind via errors.Is
+func newError(kind error, format string, args ...interface{}) error {
+	return &tomvError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+// mismatchError reports a value that can't be converted to the requested type
+//
+// The message is built when shown, so a secret value can be swapped for its
+// masked form first.
+type mismatchError struct {
+	format string // Message with a verb for the key, then one for the value
+	key    string
+	value  interface{}
+}
+
+// newMismatch creates an error matching ErrTypeMismatch that shows value with formatValue
+func newMismatch(format, key string, value interface{}) error {
+	return &mismatchError{format: format, key: key, value: value}
+}
+
+func (e *mismatchError) Error() string {
+	return fmt.Sprintf(e.format, e.key, formatValue(e.value))
+}
+
+func (e *mismatchError) Unwrap() error {
+	return ErrTypeMismatch
+}
+
+// NotFoundError reports a variable missing from every searched file, or from the file named by its prefix
+type NotFoundError struct {
+	Key           string        `json:"key"`
+	File          string        `json:"file,omitempty"` // Set when the key was scoped to one file with explicit syntax
+	SearchedFiles []string      `json:"searched_files"`
+	Suggestions   []string      `json:"suggestions,omitempty"` // Variables available in the searched files
+	Diagnostics   []*Diagnostic `json:"diagnostics,omitempty"` // Files that failed to load and may define the key
+}
+
+func (e *NotFoundError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("variable \"%s\" not found in file %s\n\nAvailable variables in %s:\n%s",
+			e.Key, e.File, e.File, formatVariablesOrNone(e.Suggestions))
+	}
+
+	if len(e.SearchedFiles) == 0 {
+		return fmt.Sprintf("variable \"%s\" not found\n\nNo TOML files found in project", e.Key) + formatDiagnostics(e.Diagnostics)
+	}
+
+	errorMsg := fmt.Sprintf("variable \"%s\" not found\n\nSearched in:", e.Key)
+	for _, file := range e.SearchedFiles {
+		errorMsg += fmt.Sprintf("\n- %s", file)
+	}
+	if len(e.Suggestions) > 0 {
+		errorMsg += "\n\nAvailable variables:\n" + formatVariablesList(e.Suggestions)
+	}
+	return errorMsg + formatDiagnostics(e.Diagnostics)
+}
+
+// Is reports whether target is ErrNotFound
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// Unwrap returns the diagnostics, so the error also matches ErrParse when a file failed to load
+func (e *NotFoundError) Unwrap() []error {
+	return diagnosticErrors(e.Diagnostics)
+}
+
+// PrefixNotFoundError reports a key addressed to a file prefix no loaded file has
+type PrefixNotFoundError struct {
+	Key         string        `json:"key"`
+	Prefix      string        `json:"prefix"`
+	Prefixes    []string      `json:"prefixes"`              // Prefixes of the loaded files
+	Files       []string      `json:"files"`                 // File behind each of Prefixes
+	Diagnostics []*Diagnostic `json:"diagnostics,omitempty"` // Files that failed to load and may own the prefix
+}
+
+func (e *PrefixNotFoundError) Error() string {
+	errorMsg := fmt.Sprintf("file prefix \"%s\" not found\n\nAvailable file prefixes:", e.Prefix)
+	for i, prefix := range e.Prefixes {
+		errorMsg += fmt.Sprintf("\n- %s (from %s)", prefix, e.Files[i])
+	}
+	return errorMsg + formatDiagnostics(e.Diagnostics)
+}
+
+// Is reports whether target is ErrNotFound
+func (e *PrefixNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// Unwrap returns the diagnostics, so the error also matches ErrParse when a file failed to load
+func (e *PrefixNotFoundError) Unwrap() []error {
+	return diagnosticErrors(e.Diagnostics)
+}
+
+// ConflictError reports an unprefixed variable or {{reference}} defined in more than one file
+type ConflictError struct {
+	Key          string     `json:"key"`
+	Files        []string   `json:"files"`
+	ExplicitKeys []string   `json:"explicit_keys"`       // Prefixed keys that select one file each
+	Positions    []Position `json:"positions,omitempty"` // Where the variable is defined in each of Files
+	InKey        string     `json:"in_key,omitempty"`    // Key holding an ambiguous reference, relative to its file
+	File         string     `json:"file,omitempty"`      // File holding an ambiguous reference
+	Position     Position   `json:"position,omitempty"`  // Where the ambiguous reference is written
+}
+
+func (e *ConflictError) Error() string {
+	errorMsg := fmt.Sprintf("variable \"%s\" found in multiple files:", e.Key)
+	if e.InKey != "" {
+		errorMsg = fmt.Sprintf("variable '%s' referenced in '%s' found in multiple files:", e.Key, e.InKey)
+	}
+	for i, file := range e.Files {
+		if i < len(e.Positions) {
+			file = locate(file, e.Positions[i])
+		}
+		errorMsg += fmt.Sprintf("\n- %s", file)
+	}
+	if e.File != "" {
+		errorMsg += fmt.Sprintf("\n\nReferenced from: %s", locate(e.File, e.Position))
+	}
+	errorMsg += "\n\nUse explicit syntax:"
+	for _, explicitKey := range e.ExplicitKeys {
+		if e.InKey != "" {
+			errorMsg += fmt.Sprintf("\n- {{%s}}", explicitKey)
+		} else {
+			errorMsg += fmt.Sprintf("\n- tomv.Get(\"%s\")", explicitKey)
+		}
+	}
+	return errorMsg
+}
+
+// Is reports whether target is ErrConflict
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// PrefixCollisionError reports files that would be addressed by the same prefix
+type PrefixCollisionError struct {
+	Prefix string   `json:"prefix"`
+	Files  []string `json:"files"`
+}
+
+func (e *PrefixCollisionError) Error() string {
+	errorMsg := fmt.Sprintf("file prefix \"%s\" is used by multiple files:", e.Prefix)
+	for _, file := range e.Files {
+		errorMsg += fmt.Sprintf("\n- %s", file)
+	}
+	return errorMsg + "\n\nUse WithPrefixStrategy(PrefixRelativePath), or name each file with a header:\n[tomv]\nnamespace = \"...\""
+}
+
+// Is reports whether target is ErrConflict
+func (e *PrefixCollisionError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// UnresolvedReferenceError reports a {{variable}} reference that points at nothing
+type UnresolvedReferenceError struct {
+	Ref         string        `json:"ref"`
+	InKey       string        `json:"in_key,omitempty"` // Key holding the reference, relative to its file
+	File        string        `json:"file,omitempty"`
+	Position    Position      `json:"position,omitempty"` // Where the reference is written
+	Available   []string      `json:"available,omitempty"`
+	Source      bool          `json:"source,omitempty"`      // Set when a registered source has no such key
+	Diagnostics []*Diagnostic `json:"diagnostics,omitempty"` // Files that failed to load and may define the variable
+}
+
+func (e *UnresolvedReferenceError) Error() string {
+	errorMsg := fmt.Sprintf("variable '%s' referenced but not found", e.Ref)
+	if e.InKey != "" {
+		errorMsg = fmt.Sprintf("variable '%s' referenced in '%s' but not found", e.Ref, e.InKey)
+	}
+	if e.File != "" {
+		errorMsg += fmt.Sprintf("\n\nReferenced from: %s", locate(e.File, e.Position))
+	}
+	if e.Source {
+		return errorMsg + "\n\nThe source has no such key" + formatDiagnostics(e.Diagnostics)
+	}
+	return errorMsg + "\n\nAvailable variables:\n" + formatVariablesOrNone(e.Available) + formatDiagnostics(e.Diagnostics)
+}
+
+// Is reports whether target is ErrNotFound
+func (e *UnresolvedReferenceError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// Unwrap returns the diagnostics, so the error also matches ErrParse when a file failed to load
+func (e *UnresolvedReferenceError) Unwrap() []error {
+	return diagnosticErrors(e.Diagnostics)
+}
+
+// MissingEnvError reports a required {{ENV.VAR:?message}} that is not set
+type MissingEnvError struct {
+	Name     string   `json:"name"`
+	Message  string   `json:"message,omitempty"`
+	InKey    string   `json:"in_key,omitempty"` // Key holding the reference, relative to its file
+	File     string   `json:"file,omitempty"`
+	Position Position `json:"position,omitempty"` // Where the reference is written
+}
+
+func (e *MissingEnvError) Error() string {
+	errorMsg := fmt.Sprintf("environment variable '%s' is not set", e.Name)
+	if e.Message != "" {
+		errorMsg = fmt.Sprintf("environment variable '%s' is not set: %s", e.Name, e.Message)
+	}
+	if e.InKey != "" {
+		errorMsg += fmt.Sprintf("\n\nRequired by '%s'", e.InKey)
+		if e.File != "" {
+			errorMsg += fmt.Sprintf(" in %s", locate(e.File, e.Position))
+		}
+	}
+	return errorMsg
+}
+
+// Is reports whether target is ErrNotFound
+func (e *MissingEnvError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// FilterError reports an unknown or failing function in a {{reference | filter}} pipeline
+type FilterError struct {
+	Filter   string   `json:"filter"`
+	Ref      string   `json:"ref"`
+	InKey    string   `json:"in_key,omitempty"` // Key holding the reference, relative to its file
+	File     string   `json:"file,omitempty"`
+	Position Position `json:"position,omitempty"` // Where the reference is written
+	Err      error    `json:"-"`
+
+	secret bool // The input is secret, so Err is left out of the message as it may echo it
+}
+
+func (e *FilterError) Error() string {
+	var cause interface{} = e.Err
+	if e.secret {
+		cause = "the secret value was rejected"
+	}
+	errorMsg := fmt.Sprintf("filter '%s' on '%s' failed: %v", e.Filter, e.Ref, cause)
+	if e.InKey != "" {
+		errorMsg = fmt.Sprintf("filter '%s' on '%s' in '%s' failed: %v", e.Filter, e.Ref, e.InKey, cause)
+	}
+	if e.File != "" {
+		errorMsg += fmt.Sprintf("\n\nReferenced from: %s", locate(e.File, e.Position))
+	}
+	return errorMsg
+}
+
+// Unwrap returns the function's error
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+// SourceError reports a registered source that failed to read a {{source.key}} reference
+type SourceError struct {
+	Source   string   `json:"source"`
+	Key      string   `json:"key"`
+	InKey    string   `json:"in_key,omitempty"` // Key holding the reference, relative to its file
+	File     string   `json:"file,omitempty"`
+	Position Position `json:"position,omitempty"` // Where the reference is written
+	Err      error    `json:"-"`
+}
+
+func (e *SourceError) Error() string {
+	errorMsg := fmt.Sprintf("source '%s' failed to read '%s': %v", e.Source, e.Key, e.Err)
+	if e.InKey != "" {
+		errorMsg = fmt.Sprintf("source '%s' failed to read '%s' for '%s': %v", e.Source, e.Key, e.InKey, e.Err)
+	}
+	if e.File != "" {
+		errorMsg += fmt.Sprintf("\n\nReferenced from: %s", locate(e.File, e.Position))
+	}
+	return errorMsg
+}
+
+// Unwrap returns the source's error
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// CycleError reports {{variable}} references that never finish resolving
+type CycleError struct {
+	Path      []string   `json:"path"`                // Variables forming the cycle, empty if no cycle could be isolated
+	Positions []Position `json:"positions,omitempty"` // Where each variable of Path is defined
+}
+
+func (e *CycleError) Error() string {
+	if len(e.Path) == 0 {
+		return "maximum resolution passes exceeded, possible unresolvable variable references"
+	}
+	errorMsg := fmt.Sprintf("circular dependency detected: %s", strings.Join(e.Path, " → "))
+	if len(e.Positions) > 0 {
+		errorMsg += "\n\nDefined at:"
+		for i, pos := range e.Positions {
+			if pos.File != "" && i < len(e.Path) && (i == 0 || e.Path[i] != e.Path[0]) {
+				errorMsg += fmt.Sprintf("\n- %s: %s", e.Path[i], pos)
+			}
+		}
+	}
+	return errorMsg
+}
+
+// Is reports whether target is ErrCircularReference
+func (e *CycleError) Is(target error) bool {
+	return target == ErrCircularReference
+}
+
+// DiagnosticKind tells why a file was left out of a load, or only partly loaded
+type DiagnosticKind string
+
+const (
+	DiagnosticParse     DiagnosticKind = "parse"     // The file failed to parse
+	DiagnosticOverlay   DiagnosticKind = "overlay"   // The file overlays an environment that is not selected
+	DiagnosticPositions DiagnosticKind = "positions" // The file loaded, but key positions and # tomv:secret markers could not be read
+)
+
+// Diagnostic reports a TOML file that was left out of a load, or a warning about one that loaded
+type Diagnostic struct {
+	Position                // Line and Column are zero when the file could not be read
+	Kind     DiagnosticKind `json:"kind"`
+	Message  string         `json:"message"`
+	Err      error          `json:"-"`
+}
+
+func (d *Diagnostic) Error() string {
+	switch d.Kind {
+	case DiagnosticOverlay:
+		return fmt.Sprintf("skipped TOML file %s: %s", d.File, d.Message)
+	case DiagnosticPositions:
+		return fmt.Sprintf("could not scan key positions of TOML file %s: %s", d.File, d.Message)
+	}
+	return fmt.Sprintf("failed to parse TOML file %s: %s", locate(d.File, d.Position), d.Message)
+}
+
+// Is reports whether target is ErrParse and the file failed to parse
+func (d *Diagnostic) Is(target error) bool {
+	return target == ErrParse && d.Kind == DiagnosticParse
+}
+
+// Unwrap returns the decoder's error
+func (d *Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// diagnosticErrors converts diagnostics for Unwrap
+func diagnosticErrors(diagnostics []*Diagnostic) []error {
+	errs := make([]error, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		errs[i] = diagnostic
+	}
+	return errs
+}
+
+// leftOut keeps the diagnostics of files that were not loaded, dropping warnings about loaded ones
+func leftOut(diagnostics []*Diagnostic) []*Diagnostic {
+	var files []*Diagnostic
+	for _, d := range diagnostics {
+		if d.Kind != DiagnosticPositions {
+			files = append(files, d)
+		}
+	}
+	return files
+}
+
+// formatDiagnostics lists files that were not loaded, for errors they may explain
+func formatDiagnostics(diagnostics []*Diagnostic) string {
+	if len(diagnostics) == 0 {
+		return ""
+	}
+	errorMsg := "\n\nFiles that were not loaded:"
+	for _, d := range diagnostics {
+		errorMsg += fmt.Sprintf("\n- %s: %s", locate(d.File, d.Position), d.Message)
+	}
+	return errorMsg
+}
+
+// locate names a file, with line and column when the position is known
+func locate(file string, pos Position) string {
+	if pos.Line == 0 {
+		return file
+	}
+	return pos.String()
+}
+
+// positionedError adds where a key is defined to an error about its value
+type positionedError struct {
+	err error
+	pos Position
+}
+
+func (e *positionedError) Error() string {
+	return fmt.Sprintf("%v\n\nDefined at: %s", e.err, e.pos)
+}
+
+func (e *positionedError) Unwrap() error {
+	return e.err
+}
+
+// atPosition annotates an error about a value with where it is defined
+func atPosition(err error, pos Position) error {
+	if err == nil || pos.Line == 0 {
+		return err
+	}
+	return &positionedError{err: err, pos: pos}
+}
+
+// formatVariablesOrNone formats a variable list, with a placeholder when it is empty
+func formatVariablesOrNone(keys []string) string {
+	if len(keys) == 0 {
+		return "- (no variables found)"
+	}
+	return formatVariablesList(keys)
+}
+
+// Violation is one schema rule broken by the configuration
+type Violation struct {
+	Key      string   `json:"key"`
+	Position Position `json:"position"` // Zero for missing keys
+	Message  string   `json:"message"`
+}
+
+func (v *Violation) Error() string {
+	if v.Position.File == "" {
+		return fmt.Sprintf("%s: %s", v.Key, v.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", v.Position, v.Key, v.Message)
+}
+
+// Is reports whether target is ErrValidation
+func (v *Violation) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// ValidationError aggregates every violation found by Validate
+type ValidationError struct {
+	Violations []*Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	errorMsg := fmt.Sprintf("configuration does not match schema, %d violation(s):", len(e.Violations))
+	for _, violation := range e.Violations {
+		errorMsg += "\n- " + violation.Error()
+	}
+	return errorMsg
+}
+
+// Unwrap exposes the violations to errors.Is and errors.As
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Violations))
+	for i, violation := range e.Violations {
+		errs[i] = violation
+	}
+	return errs
+}
@@ -0,0 +1,215 @@
+package tomv
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Func transforms a value in a {{reference | name arg...}} pipeline
+//
+// Functions only see the value and their literal arguments, so configuration
+// files can't reach the filesystem, network or process state through them.
+type Func func(value string, args ...string) (string, error)
+
+var (
+	funcsMutex sync.RWMutex
+	funcs      = map[string]Func{
+		"upper":   func(value string, args ...string) (string, error) { return strings.ToUpper(value), nil },
+		"lower":   func(value string, args ...string) (string, error) { return strings.ToLower(value), nil },
+		"trim":    func(value string, args ...string) (string, error) { return strings.TrimSpace(value), nil },
+		"join":    joinFunc,
+		"int":     intFunc,
+		"default": defaultFunc,
+		"base64encode": func(value string, args ...string) (string, error) {
+			return base64.StdEncoding.EncodeToString([]byte(value)), nil
+		},
+		"base64decode": base64DecodeFunc,
+		"urlencode":    func(value string, args ...string) (string, error) { return url.QueryEscape(value), nil },
+	}
+)
+
+// RegisterFunc makes fn available to {{reference | name}} pipelines, replacing any function with that name
+//
+// A configuration naming an unknown function fails to load, so register
+// functions from an init function or before creating a Config.
+func RegisterFunc(name string, fn Func) {
+	funcsMutex.Lock()
+	defer funcsMutex.Unlock()
+
+	funcs[name] = fn
+}
+
+// findFunc returns the function registered under name
+func findFunc(name string) (Func, bool) {
+	funcsMutex.RLock()
+	defer funcsMutex.RUnlock()
+
+	fn, exists := funcs[name]
+	return fn, exists
+}
+
+// filter is one stage of a pipeline: a function name and its literal arguments
+type filter struct {
+	name string
+	args []string
+}
+
+// parsePipeline splits "db.name | join \"logs\" | upper" into the reference and its filters
+//
+// The operand of an ENV operator may hold literal | characters, as in
+// {{ENV.SEP:-a|b}}: there a | only starts a filter when a registered
+// function name follows it.
+func parsePipeline(expr string) (string, []filter, error) {
+	stages, err := splitPipeline(expr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if match := envPattern.FindStringSubmatch(strings.TrimSpace(stages[0])); match != nil && match[2] != "" {
+		for len(stages) > 1 && !startsWithFunc(stages[1]) {
+			stages = append([]string{stages[0] + "|" + stages[1]}, stages[2:]...)
+		}
+	}
+
+	var filters []filter
+	for _, stage := range stages[1:] {
+		words, err := splitWords(stage)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(words) == 0 {
+			return "", nil, newError(ErrParse, "empty filter in \"%s\"", expr)
+		}
+		filters = append(filters, filter{name: words[0], args: words[1:]})
+	}
+	return strings.TrimSpace(stages[0]), filters, nil
+}
+
+// splitPipeline splits an expression on | characters outside double quotes
+func splitPipeline(expr string) ([]string, error) {
+	var stages []string
+	start := 0
+	inQuotes := false
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '\\':
+			if inQuotes {
+				i++ // Skip the escaped character
+			}
+		case '"':
+			inQuotes = !inQuotes
+		case '|':
+			if !inQuotes {
+				stages = append(stages, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if inQuotes {
+		return nil, newError(ErrParse, "unterminated string in \"%s\"", expr)
+	}
+	return append(stages, expr[start:]), nil
+}
+
+// startsWithFunc reports whether a pipeline stage names a registered function
+func startsWithFunc(stage string) bool {
+	name, _, _ := strings.Cut(strings.TrimSpace(stage), " ")
+	_, exists := findFunc(name)
+	return exists
+}
+
+// splitWords splits a filter into whitespace-separated words, unquoting "quoted strings"
+func splitWords(stage string) ([]string, error) {
+	var words []string
+	for stage = strings.TrimSpace(stage); stage != ""; stage = strings.TrimSpace(stage) {
+		if stage[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(stage)
+			if err != nil {
+				return nil, newError(ErrParse, "invalid string in \"%s\": %v", stage, err)
+			}
+			word, _ := strconv.Unquote(quoted)
+			words = append(words, word)
+			stage = stage[len(quoted):]
+			continue
+		}
+
+		end := strings.IndexAny(stage, " \t")
+		if end < 0 {
+			end = len(stage)
+		}
+		words = append(words, stage[:end])
+		stage = stage[end:]
+	}
+	return words, nil
+}
+
+// hasFilter reports whether a pipeline uses the named function
+func hasFilter(filters []filter, name string) bool {
+	for _, f := range filters {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyFilters runs a value through every filter in order
+func applyFilters(value string, filters []filter) (string, error) {
+	for _, f := range filters {
+		fn, exists := findFunc(f.name)
+		if !exists {
+			return "", &FilterError{Filter: f.name, Err: newError(ErrParse, "unknown function \"%s\"", f.name)}
+		}
+
+		result, err := fn(value, f.args...)
+		if err != nil {
+			return "", &FilterError{Filter: f.name, Err: err}
+		}
+		value = result
+	}
+	return value, nil
+}
+
+// joinFunc appends path segments with a single slash between them
+func joinFunc(value string, args ...string) (string, error) {
+	for _, arg := range args {
+		value = strings.TrimRight(value, "/") + "/" + strings.TrimLeft(arg, "/")
+	}
+	return value, nil
+}
+
+// intFunc checks the value is an integer, normalizing surrounding whitespace
+func intFunc(value string, args ...string) (string, error) {
+	i, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return "", newError(ErrTypeMismatch, "\"%s\" is not a valid integer", value)
+	}
+	return strconv.Itoa(i), nil
+}
+
+// defaultFunc replaces an empty or missing value
+func defaultFunc(value string, args ...string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("default takes exactly one argument, got %d", len(args))
+	}
+	if value == "" {
+		return args[0], nil
+	}
+	return value, nil
+}
+
+// base64DecodeFunc decodes standard base64, padded or not
+func base64DecodeFunc(value string, args ...string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		decoded, err = base64.RawStdEncoding.DecodeString(value)
+	}
+	if err != nil {
+		return "", newError(ErrTypeMismatch, "value is not valid base64: %v", err)
+	}
+	return string(decoded), nil
+}
@@ -2,4 +2,4 @@ module github.com/DeprecatedLuar/toml-vars-letsgooo
 
 go 1.24.6
 
-require github.com/BurntSushi/toml v1.5.0 // indirect
+require github.com/BurntSushi/toml v1.5.0
@@ -0,0 +1,85 @@
+package tomv
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// environmentVar selects overlay layers when WithEnvironment isn't used, e.g. TOMV_ENV=production,local
+const environmentVar = "TOMV_ENV"
+
+// activeEnvironments returns the selected overlay layers, lowest precedence first
+func (c *Config) activeEnvironments() []string {
+	if len(c.environments) > 0 {
+		return c.environments
+	}
+	return splitList(os.Getenv(environmentVar))
+}
+
+// groupLayers separates base files from their overlays
+//
+// A file named <base>.<env>.toml next to <base>.toml is an overlay. Overlays of
+// selected environments are returned per base path in environment order, the
+// others are returned as skipped so they never show up as unrelated files.
+func groupLayers(files []string, environments []string) ([]string, map[string][]string, []string) {
+	present := make(map[string]bool, len(files))
+	for _, file := range files {
+		present[file] = true
+	}
+
+	rank := make(map[string]int, len(environments))
+	for i, env := range environments {
+		rank[env] = i
+	}
+
+	var bases, skipped []string
+	overlays := make(map[string][]string)
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		dot := strings.LastIndex(name, ".")
+		if dot <= 0 {
+			bases = append(bases, file)
+			continue
+		}
+
+		basePath := filepath.Join(filepath.Dir(file), name[:dot]+filepath.Ext(file))
+		if !present[basePath] {
+			bases = append(bases, file) // Dotted name without a base is a standalone file
+			continue
+		}
+		if _, selected := rank[name[dot+1:]]; selected {
+			overlays[basePath] = append(overlays[basePath], file)
+		} else {
+			skipped = append(skipped, file)
+		}
+	}
+
+	for base, layers := range overlays {
+		sort.SliceStable(layers, func(i, j int) bool {
+			return rank[layerName(layers[i])] < rank[layerName(layers[j])]
+		})
+		overlays[base] = layers
+	}
+	return bases, overlays, skipped
+}
+
+// layerName extracts the environment of an overlay file (app.production.toml -> production)
+func layerName(file string) string {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// mergeTables deep-merges src into dst table by table, values from src win
+func mergeTables(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcTable, srcIsTable := value.(map[string]interface{})
+		dstTable, dstIsTable := dst[key].(map[string]interface{})
+		if srcIsTable && dstIsTable {
+			mergeTables(dstTable, srcTable)
+			continue
+		}
+		dst[key] = value
+	}
+}
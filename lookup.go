@@ -0,0 +1,138 @@
+package tomv
+
+import (
+	"strings"
+	"time"
+)
+
+// Lookup retrieves a string value by key, returns an error if not found
+func (c *Config) Lookup(key string) (string, error) {
+	return lookupAs(c.getEntry, key, asString)
+}
+
+// LookupInt retrieves an integer value by key, returns an error if not found or invalid
+func (c *Config) LookupInt(key string) (int, error) {
+	return lookupAs(c.getEntry, key, asInt)
+}
+
+// LookupBool retrieves a boolean value by key, returns an error if not found or invalid
+func (c *Config) LookupBool(key string) (bool, error) {
+	return lookupAs(c.getEntry, key, asBool)
+}
+
+// LookupFloat retrieves a float64 value by key, returns an error if not found or invalid
+func (c *Config) LookupFloat(key string) (float64, error) {
+	return lookupAs(c.getEntry, key, asFloat)
+}
+
+// LookupDuration retrieves a time.Duration value by key, returns an error if not found or invalid
+func (c *Config) LookupDuration(key string) (time.Duration, error) {
+	return lookupAs(c.getEntry, key, asDuration)
+}
+
+// LookupTime retrieves a TOML datetime value by key, returns an error if not found or invalid
+func (c *Config) LookupTime(key string) (time.Time, error) {
+	return lookupAs(c.getEntry, key, asTime)
+}
+
+// LookupStringSlice retrieves a TOML array or comma-separated string as a slice, returns an error if not found
+func (c *Config) LookupStringSlice(key string) ([]string, error) {
+	return lookupAs(c.getEntry, key, asStringSlice)
+}
+
+// LookupIntSlice retrieves a TOML integer array or comma-separated string as an int slice, returns an error if not found or invalid
+func (c *Config) LookupIntSlice(key string) ([]int, error) {
+	return lookupAs(c.getEntry, key, asIntSlice)
+}
+
+// LookupSecret retrieves a value by key as a Secret that prints as ****, returns an error if not found
+func (c *Config) LookupSecret(key string) (Secret, error) {
+	return lookupAs(c.getEntry, key, asSecret)
+}
+
+// reader returns the cache entry of a key, Config and View read through one so values convert the same way
+type reader func(key string) cacheEntry
+
+// lookupAs retrieves a value converted by convert, describing conversion errors with where the key is defined
+func lookupAs[T any](r reader, key string, convert func(key string, value interface{}) (T, error)) (T, error) {
+	entry := r(key)
+	if entry.err != nil {
+		var zero T
+		return zero, entry.err
+	}
+	result, err := convert(key, entry.value)
+	return result, entry.describe(err)
+}
+
+// parseBool accepts the boolean spellings supported by GetBool
+func parseBool(value string) (bool, bool) {
+	switch value {
+	case "true", "True", "TRUE", "yes", "Yes", "YES", "1":
+		return true, true
+	case "false", "False", "FALSE", "no", "No", "NO", "0":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// splitList splits a comma-separated value and trims whitespace around each item
+func splitList(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+
+	parts := strings.Split(value, ",")
+	result := make([]string, len(parts))
+	for i, part := range parts {
+		result[i] = strings.TrimSpace(part)
+	}
+	return result
+}
+
+// Package-level functions operate on the default Config
+
+// Lookup retrieves a string value by key, returns an error if not found
+func Lookup(key string) (string, error) {
+	return defaultConfig.Lookup(key)
+}
+
+// LookupInt retrieves an integer value by key, returns an error if not found or invalid
+func LookupInt(key string) (int, error) {
+	return defaultConfig.LookupInt(key)
+}
+
+// LookupBool retrieves a boolean value by key, returns an error if not found or invalid
+func LookupBool(key string) (bool, error) {
+	return defaultConfig.LookupBool(key)
+}
+
+// LookupFloat retrieves a float64 value by key, returns an error if not found or invalid
+func LookupFloat(key string) (float64, error) {
+	return defaultConfig.LookupFloat(key)
+}
+
+// LookupDuration retrieves a time.Duration value by key, returns an error if not found or invalid
+func LookupDuration(key string) (time.Duration, error) {
+	return defaultConfig.LookupDuration(key)
+}
+
+// LookupTime retrieves a TOML datetime value by key, returns an error if not found or invalid
+func LookupTime(key string) (time.Time, error) {
+	return defaultConfig.LookupTime(key)
+}
+
+// LookupStringSlice retrieves a TOML array or comma-separated string as a slice, returns an error if not found
+func LookupStringSlice(key string) ([]string, error) {
+	return defaultConfig.LookupStringSlice(key)
+}
+
+// LookupIntSlice retrieves a TOML integer array or comma-separated string as an int slice, returns an error if not found or invalid
+func LookupIntSlice(key string) ([]int, error) {
+	return defaultConfig.LookupIntSlice(key)
+}
+
+// LookupSecret retrieves a value from the default Config as a Secret, returns an error if not found
+func LookupSecret(key string) (Secret, error) {
+	return defaultConfig.LookupSecret(key)
+}
@@ -1,20 +1,25 @@
 package tomv
 
 import (
-	"fmt"
+	"errors"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-// variablePattern matches {{section.key}} patterns
-var variablePattern = regexp.MustCompile(`\{\{([^}]+)\}\}`)
+// variablePattern matches {{section.key}} patterns, and escaped \{{section.key}} ones so they can be skipped
+var variablePattern = regexp.MustCompile(`\\?\{\{([^}]+)\}\}`)
 
-// envPattern matches {{ENV.VAR:-default}} patterns
-var envPattern = regexp.MustCompile(`^ENV\.([A-Z_][A-Z0-9_]*)(?::-(.*))?$`)
+// envPattern matches {{ENV.VAR}} with an optional shell operator: -, :-, ?, :?, + or :+
+var envPattern = regexp.MustCompile(`^ENV\.([A-Za-z_][A-Za-z0-9_]*)(?:(:?[-?+])(.*))?$`)
 
 // resolveVariables processes a TOML data structure and resolves all {{variable}} references
-func resolveVariables(data map[string]interface{}) (map[string]interface{}, error) {
+//
+// With explicitPrefixes, only app:server.port references select a file by
+// prefix. Source references are answered through sources.
+func resolveVariables(data map[string]interface{}, explicitPrefixes bool, sources *sourceLoad) (map[string]interface{}, error) {
 	// Create a working copy to avoid modifying original data
 	resolved := deepCopyMap(data)
 
@@ -23,7 +28,7 @@ func resolveVariables(data map[string]interface{}) (map[string]interface{}, erro
 	for pass := 0; pass < maxPasses; pass++ {
 		changed := false
 
-		err := processMapForVariables(resolved, resolved, &changed)
+		err := processMapForVariables(resolved, resolved, "", &changed, explicitPrefixes, sources)
 		if err != nil {
 			return nil, err
 		}
@@ -31,202 +36,533 @@ func resolveVariables(data map[string]interface{}) (map[string]interface{}, erro
 		// If no changes were made, check if we still have unresolved variables
 		if !changed {
 			if hasUnresolvedVariables(resolved) {
-				return nil, detectCircularDependencies(resolved)
+				return nil, detectCircularDependencies(resolved, explicitPrefixes)
 			}
 			break
 		}
 
 		// If we've reached max passes, check for circular dependencies
 		if pass == maxPasses-1 {
-			return nil, detectCircularDependencies(resolved)
+			return nil, detectCircularDependencies(resolved, explicitPrefixes)
 		}
 	}
 
+	unescapeBraces(resolved)
 	return resolved, nil
 }
 
 // processMapForVariables recursively processes all values in a map for variable substitution
-func processMapForVariables(current map[string]interface{}, root map[string]interface{}, changed *bool) error {
+// path is the dotted location of current within root, used to annotate errors
+func processMapForVariables(current map[string]interface{}, root map[string]interface{}, path string, changed *bool, explicitPrefixes bool, sources *sourceLoad) error {
 	for key, value := range current {
-		switch v := value.(type) {
-		case string:
-			// Process string values for variable substitution
-			resolved, hasVariables, err := resolveStringVariables(v, root)
-			if err != nil {
-				return err
+		resolved, err := processValueForVariables(value, root, joinPath(path, key), changed, explicitPrefixes, sources)
+		if err != nil {
+			return err
+		}
+		current[key] = resolved
+	}
+	return nil
+}
+
+// processValueForVariables substitutes variables in a single value, descending into tables and arrays
+func processValueForVariables(value interface{}, root map[string]interface{}, fullKey string, changed *bool, explicitPrefixes bool, sources *sourceLoad) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		// Root is namespaced by file prefix: prefix.section.key
+		file, inKey, _ := strings.Cut(fullKey, ".")
+
+		// Process string values for variable substitution, whole-value references keep their type
+		var resolved interface{}
+		var hasVariables bool
+		var err error
+		if expr, whole := wholeReference(v); whole {
+			resolved, hasVariables, err = resolveWholeReference(v, expr, root, fullKey, explicitPrefixes, sources)
+		} else {
+			resolved, hasVariables, err = resolveStringVariables(v, root, fullKey, explicitPrefixes, sources)
+		}
+		if err != nil {
+			var refErr *UnresolvedReferenceError
+			if errors.As(err, &refErr) && refErr.InKey == "" {
+				refErr.File, refErr.InKey = file, inKey
+			}
+			var conflictErr *ConflictError
+			if errors.As(err, &conflictErr) && conflictErr.InKey == "" {
+				conflictErr.File, conflictErr.InKey = file, inKey
 			}
-			if hasVariables && resolved != v {
-				current[key] = resolved
-				*changed = true
+			var envErr *MissingEnvError
+			if errors.As(err, &envErr) && envErr.InKey == "" {
+				envErr.File, envErr.InKey = file, inKey
 			}
-		case map[string]interface{}:
-			// Recursively process nested maps
-			err := processMapForVariables(v, root, changed)
+			var filterErr *FilterError
+			if errors.As(err, &filterErr) && filterErr.InKey == "" {
+				filterErr.File, filterErr.InKey = file, inKey
+			}
+			var sourceErr *SourceError
+			if errors.As(err, &sourceErr) && sourceErr.InKey == "" {
+				sourceErr.File, sourceErr.InKey = file, inKey
+			}
+			return nil, err
+		}
+		if text, isString := resolved.(string); hasVariables && (!isString || text != v) {
+			*changed = true
+			return resolved, nil
+		}
+	case map[string]interface{}:
+		// Recursively process nested maps
+		if err := processMapForVariables(v, root, fullKey, changed, explicitPrefixes, sources); err != nil {
+			return nil, err
+		}
+	case []interface{}:
+		// Arrays are addressed by index: hosts.0
+		for i, item := range v {
+			resolved, err := processValueForVariables(item, root, fullKey+"."+strconv.Itoa(i), changed, explicitPrefixes, sources)
 			if err != nil {
-				return err
+				return nil, err
+			}
+			v[i] = resolved
+		}
+	case []map[string]interface{}:
+		// Arrays of tables ([[upstreams]]) are addressed by index: upstreams.0.url
+		for i, item := range v {
+			if err := processMapForVariables(item, root, fullKey+"."+strconv.Itoa(i), changed, explicitPrefixes, sources); err != nil {
+				return nil, err
 			}
 		}
 	}
-	return nil
+	return value, nil
 }
 
-// resolveStringVariables resolves all {{variable}} patterns in a string
-func resolveStringVariables(str string, data map[string]interface{}) (string, bool, error) {
-	hasVariables := false
-	result := str
+// wholeReference reports whether a string is exactly one unescaped {{reference}} and returns its expression
+func wholeReference(str string) (string, bool) {
+	match := variablePattern.FindStringSubmatchIndex(str)
+	if match == nil || match[0] != 0 || match[1] != len(str) || str[0] == '\\' {
+		return "", false
+	}
+	return str[match[2]:match[3]], true
+}
 
-	// Find all {{variable}} matches
-	matches := variablePattern.FindAllStringSubmatch(str, -1)
+// resolveWholeReference resolves a value that is a single reference without turning it into text
+//
+// A plain internal reference copies the referenced value, tables and arrays
+// included. ENV references, sources and pipelines produce text, which becomes
+// an integer or boolean when it reads as one exactly. Internal keys win over
+// a source of the same name.
+func resolveWholeReference(str, expr string, data map[string]interface{}, fullKey string, explicitPrefixes bool, sources *sourceLoad) (interface{}, bool, error) {
+	path, filters, err := parsePipeline(expr)
+	if err != nil {
+		return nil, true, err
+	}
 
-	for _, match := range matches {
-		if len(match) != 2 {
-			continue
+	if len(filters) == 0 && !envPattern.MatchString(path) {
+		qualified, found, err := qualifyReference(path, data, fullKey, explicitPrefixes)
+		if err != nil {
+			return nil, true, err
+		}
+		if found {
+			value, _ := resolveKey(data, qualified)
+			if valueHasUnresolvedVariables(value) {
+				return str, true, nil // Resolved in a later pass
+			}
+			return deepCopyValue(value), true, nil
+		}
+		// Not found: fall through to sources, or an error matching the interpolated case
+	}
+
+	text, hasVariables, err := resolveStringVariables(str, data, fullKey, explicitPrefixes, sources)
+	if err != nil || hasReferences(text) {
+		return text, hasVariables, err
+	}
+	return inferScalar(text), hasVariables, nil
+}
+
+// inferScalar types text that reads exactly as a TOML integer or boolean, anything else stays a string
+func inferScalar(text string) interface{} {
+	switch text {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil && strconv.FormatInt(i, 10) == text {
+		return i
+	}
+	return text
+}
+
+// resolveStringVariables resolves all {{variable}} patterns in the string held by fullKey (prefix.section.key)
+func resolveStringVariables(str string, data map[string]interface{}, fullKey string, explicitPrefixes bool, sources *sourceLoad) (string, bool, error) {
+	hasVariables := false
+	var result strings.Builder
+	last := 0
+
+	// Find all {{variable}} matches, escaped \{{...}} ones included so they are skipped as a whole
+	for _, match := range variablePattern.FindAllStringSubmatchIndex(str, -1) {
+		placeholder := str[match[0]:match[1]] // Full match: {{section.key | filter}}
+		if strings.HasPrefix(placeholder, `\`) {
+			continue // Literal braces, unescaped once resolution is done
 		}
 
 		hasVariables = true
-		placeholder := match[0]  // Full match: {{section.key}}
-		variablePath := match[1] // Just the path: section.key
+		value := placeholder // Kept until it can be resolved
+
+		// Just the path: section.key, followed by optional | filters
+		variablePath, filters, err := parsePipeline(str[match[2]:match[3]])
+		if err != nil {
+			return "", hasVariables, err
+		}
 
 		// Check if this is an environment variable
 		if envMatch := envPattern.FindStringSubmatch(variablePath); envMatch != nil {
-			envVar := envMatch[1]
-			defaultValue := ""
-			if len(envMatch) > 2 && envMatch[2] != "" {
-				defaultValue = envMatch[2]
+			// If the resulting value contains variables, it will be resolved in next pass
+			expanded, err := expandEnv(envMatch[1], envMatch[2], envMatch[3])
+			if err != nil {
+				return "", hasVariables, err
 			}
-
-			value := os.Getenv(envVar)
-			if value == "" {
-				value = defaultValue
-				// If default value contains variables, it will be resolved in next pass
-				if strings.Contains(value, "{{") {
-					// Replace this ENV variable with its default value for next pass
-					result = strings.ReplaceAll(result, placeholder, value)
-					continue
+			if value, err = applyFilters(expanded, filters); err != nil {
+				return "", hasVariables, withFilterRef(err, variablePath)
+			}
+		} else {
+			// Resolve internal variable, which takes precedence over a source of the same name
+			resolved, found, err := resolveVariablePath(variablePath, data, fullKey, explicitPrefixes)
+			if err != nil {
+				return "", hasVariables, err
+			}
+			fromSource := false
+			if !found {
+				// Resolve through a registered source such as file. or dotenv.
+				if source, key, ok := findSource(variablePath); ok {
+					if resolved, found, err = source.lookup(sources, key); err != nil {
+						name, _, _ := strings.Cut(variablePath, ".")
+						return "", hasVariables, &SourceError{Source: name, Key: key, Err: err}
+					}
+					if !found && !hasFilter(filters, "default") {
+						return "", hasVariables, &UnresolvedReferenceError{Ref: variablePath, Source: true}
+					}
+					fromSource = true
+				}
+			}
+			if !found && !fromSource {
+				if !hasFilter(filters, "default") {
+					var available []string
+					collectKeys(data, "", &available)
+					return "", hasVariables, &UnresolvedReferenceError{Ref: variablePath, Available: available}
 				}
+				resolved = "" // Missing values fall through to the default filter
 			}
 
-			// Replace the placeholder with the resolved environment value
-			result = strings.ReplaceAll(result, placeholder, value)
-			continue
+			// Check if the resolved value still contains variables (for multi-pass), source values are final
+			if fromSource || !hasReferences(resolved) {
+				if value, err = applyFilters(resolved, filters); err != nil {
+					return "", hasVariables, withFilterRef(err, variablePath)
+				}
+			}
 		}
 
-		// Resolve internal variable
-		value, found := resolveVariablePath(variablePath, data)
-		if !found {
-			return "", hasVariables, fmt.Errorf("variable '%s' referenced but not found\n\nAvailable variables:\n%s",
-				variablePath, getAvailableVariablesList(data))
-		}
+		// Replace the placeholder with the resolved value
+		result.WriteString(str[last:match[0]])
+		result.WriteString(value)
+		last = match[1]
+	}
 
-		// Check if the resolved value still contains variables (for multi-pass)
-		if strings.Contains(value, "{{") {
-			// Still has unresolved variables - will be processed in next pass
-			continue
+	if !hasVariables {
+		return str, false, nil
+	}
+	result.WriteString(str[last:])
+	return result.String(), true, nil
+}
+
+// withFilterRef records which reference a failing filter was applied to
+func withFilterRef(err error, ref string) error {
+	var filterErr *FilterError
+	if errors.As(err, &filterErr) {
+		filterErr.Ref = ref
+	}
+	return err
+}
+
+// expandEnv applies a shell parameter expansion operator to an environment variable
+//
+// Without a colon the operators only test whether the variable is set, with a
+// colon an empty value counts as unset, as in ${VAR-default} vs ${VAR:-default}.
+func expandEnv(name, operator, arg string) (string, error) {
+	value, set := os.LookupEnv(name)
+	if strings.HasPrefix(operator, ":") && value == "" {
+		set = false
+	}
+
+	switch strings.TrimPrefix(operator, ":") {
+	case "-":
+		if !set {
+			return arg, nil
 		}
+	case "?":
+		if !set {
+			return "", &MissingEnvError{Name: name, Message: arg}
+		}
+	case "+":
+		if set {
+			return arg, nil
+		}
+		return "", nil
+	}
+	return value, nil
+}
 
-		// Replace the placeholder with the resolved value
-		result = strings.ReplaceAll(result, placeholder, value)
+// hasReferences reports whether a string holds {{variable}} references that are not escaped
+func hasReferences(str string) bool {
+	for _, match := range variablePattern.FindAllString(str, -1) {
+		if !strings.HasPrefix(match, `\`) {
+			return true
+		}
 	}
+	return false
+}
 
-	return result, hasVariables, nil
+// unescapeBraces turns every escaped \{{ into literal {{ once resolution is finished
+func unescapeBraces(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.ReplaceAll(v, `\{{`, "{{")
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = unescapeBraces(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = unescapeBraces(item)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			unescapeBraces(item)
+		}
+	}
+	return value
 }
 
 // resolveVariablePath resolves a dot-notation path like "section.key" to its value
-// In namespaced structure, searches across all files
-func resolveVariablePath(path string, data map[string]interface{}) (string, bool) {
-	parts := strings.Split(path, ".")
+// In namespaced structure, follows the same rules as qualifyReference
+func resolveVariablePath(path string, data map[string]interface{}, fullKey string, explicitPrefixes bool) (string, bool, error) {
+	qualified, found, err := qualifyReference(path, data, fullKey, explicitPrefixes)
+	if !found {
+		return "", false, err
+	}
+	value, found := resolveKeyInData(qualified, data)
+	return value, found, nil
+}
+
+// qualifyReference maps a reference held by fullKey to the file-prefixed key it points at
+//
+// Relative references are anchored at fullKey and app:server.port names its
+// file. Otherwise the file holding the reference wins, then a file prefix
+// guessed from the first segment unless explicitPrefixes disables guessing,
+// then a unique match in another file. A reference found in several other
+// files is ambiguous and reported as a ConflictError.
+func qualifyReference(path string, data map[string]interface{}, fullKey string, explicitPrefixes bool) (string, bool, error) {
+	if qualified, relative := relativeReference(path, data, fullKey); relative {
+		if qualified == "" {
+			return "", false, nil // Climbed above the file
+		}
+		_, found := resolveKey(data, qualified)
+		return qualified, found, nil
+	}
+
+	if prefix, remainingPath, ok := splitExplicitKey(path); ok {
+		fileMap, ok := data[prefix].(map[string]interface{})
+		if !ok {
+			return "", false, nil
+		}
+		_, found := resolveKey(fileMap, remainingPath)
+		return prefix + "." + remainingPath, found, nil
+	}
+
+	file, _, _ := strings.Cut(fullKey, ".")
+
+	// Prefer the file containing the reference
+	if fileMap, ok := data[file].(map[string]interface{}); ok {
+		if _, found := resolveKey(fileMap, path); found {
+			return file + "." + path, true, nil
+		}
+	}
 
 	// If this looks like a file-prefixed path (file.section.key), try direct resolution
-	if len(parts) >= 2 {
-		if fileData, exists := data[parts[0]]; exists {
-			if fileMap, ok := fileData.(map[string]interface{}); ok {
-				remainingPath := strings.Join(parts[1:], ".")
-				return resolveKeyInData(remainingPath, fileMap)
+	if prefix, remainingPath, ok := strings.Cut(path, "."); ok && !explicitPrefixes {
+		if fileMap, ok := data[prefix].(map[string]interface{}); ok {
+			if _, found := resolveKey(fileMap, remainingPath); found {
+				return path, true, nil
 			}
 		}
 	}
 
-	// Search across all files for the variable
-	for _, fileData := range data {
+	// Search the other files, which must agree on a single match
+	var matches []string
+	for prefix, fileData := range data {
+		if prefix == file {
+			continue
+		}
 		if fileMap, ok := fileData.(map[string]interface{}); ok {
-			if value, found := resolveKeyInData(path, fileMap); found {
-				return value, true
+			if _, found := resolveKey(fileMap, path); found {
+				matches = append(matches, prefix)
 			}
 		}
 	}
 
-	return "", false
+	switch len(matches) {
+	case 0:
+		return "", false, nil
+	case 1:
+		return matches[0] + "." + path, true, nil
+	}
+
+	sort.Strings(matches)
+	explicitKeys := make([]string, len(matches))
+	for i, prefix := range matches {
+		explicitKeys[i] = explicitKey(prefix, path, explicitPrefixes)
+	}
+	return "", false, &ConflictError{Key: path, Files: matches, ExplicitKeys: explicitKeys}
 }
 
-// resolveKeyInData resolves a key within a specific data structure
-func resolveKeyInData(path string, data map[string]interface{}) (string, bool) {
-	parts := strings.Split(path, ".")
-
-	current := data
-	for i, part := range parts {
-		if i == len(parts)-1 {
-			// Last part - get the actual value
-			if value, exists := current[part]; exists {
-				return fmt.Sprintf("%v", value), true
-			}
+// relativeReference anchors {{.key}}, {{..key}} and {{self.key}} at the key holding the reference
+//
+// One leading dot addresses the table holding fullKey and every further dot
+// climbs one table up, while self addresses the enclosing file. Array
+// elements belong to the table holding the array. An empty result means the
+// reference climbs above its file. A real top-level [self] table in any file
+// keeps its meaning, so self.key then resolves like any other reference.
+func relativeReference(path string, data map[string]interface{}, fullKey string) (string, bool) {
+	parts := strings.Split(fullKey, ".")
+	for len(parts) > 2 {
+		if _, err := strconv.Atoi(parts[len(parts)-1]); err != nil {
+			break
+		}
+		// Numeric segments are table keys ([codes] 404 = ...) unless their parent is an array
+		if !isArrayAt(data, parts[:len(parts)-1]) {
+			break
+		}
+		parts = parts[:len(parts)-1] // hosts.0 -> hosts
+	}
+
+	if rest, ok := strings.CutPrefix(path, "self."); ok {
+		if hasSelfTable(data) {
 			return "", false
-		} else {
-			// Intermediate part - must be a map
-			if nextMap, exists := current[part]; exists {
-				if typedMap, ok := nextMap.(map[string]interface{}); ok {
-					current = typedMap
-				} else {
-					return "", false
-				}
-			} else {
-				return "", false
+		}
+		return parts[0] + "." + rest, true
+	}
+	if !strings.HasPrefix(path, ".") {
+		return "", false
+	}
+
+	rest := strings.TrimLeft(path, ".")
+	depth := len(path) - len(rest) // 1 = same table, 2 = parent, ...
+	table := parts[:len(parts)-1]  // The table holding fullKey, the file prefix must remain
+	for ; depth > 1 && len(table) > 0; depth-- {
+		last := table[len(table)-1]
+		table = table[:len(table)-1]
+		// Leaving an element of an array of tables leaves the array too: up.0 -> the table holding up
+		if _, err := strconv.Atoi(last); err == nil && len(table) > 1 && isArrayAt(data, table) {
+			table = table[:len(table)-1]
+		}
+	}
+	if len(table) < 1 || rest == "" {
+		return "", true
+	}
+	return strings.Join(table, ".") + "." + rest, true
+}
+
+// isArrayAt reports whether the dotted path parts name an array
+func isArrayAt(data map[string]interface{}, parts []string) bool {
+	value, _ := resolveKey(data, strings.Join(parts, "."))
+	switch value.(type) {
+	case []interface{}, []map[string]interface{}:
+		return true
+	}
+	return false
+}
+
+// hasSelfTable reports whether any file defines a top-level self key, which {{self.key}} must not shadow
+func hasSelfTable(data map[string]interface{}) bool {
+	for _, fileData := range data {
+		if fileMap, ok := fileData.(map[string]interface{}); ok {
+			if _, exists := fileMap["self"]; exists {
+				return true
 			}
 		}
 	}
+	return false
+}
 
-	return "", false
+// resolveKeyInData resolves a key within a specific data structure
+func resolveKeyInData(path string, data map[string]interface{}) (string, bool) {
+	value, found := resolveKey(data, path)
+	if !found {
+		return "", false
+	}
+	return formatValue(value), true
 }
 
 // detectCircularDependencies checks for circular dependencies after max passes
-func detectCircularDependencies(data map[string]interface{}) error {
+func detectCircularDependencies(data map[string]interface{}, explicitPrefixes bool) error {
 	// Build dependency graph
 	dependencies := make(map[string][]string)
 	collectDependencies(data, "", dependencies)
 
+	// Point references at the file-prefixed keys they resolve to
+	for variable, refs := range dependencies {
+		for i, ref := range refs {
+			if qualified, found, _ := qualifyReference(ref, data, variable, explicitPrefixes); found {
+				refs[i] = qualified
+			}
+		}
+	}
+
+	// Visit variables in a stable order so the reported cycle is deterministic
+	variables := make([]string, 0, len(dependencies))
+	for variable := range dependencies {
+		variables = append(variables, variable)
+	}
+	sort.Strings(variables)
+
 	// Check for cycles using DFS
 	visited := make(map[string]bool)
 	recStack := make(map[string]bool)
 
-	for variable := range dependencies {
+	for _, variable := range variables {
 		if hasCycle(variable, dependencies, visited, recStack) {
 			cycle := findCycle(variable, dependencies)
-			return fmt.Errorf("circular dependency detected: %s", strings.Join(cycle, " → "))
+			return &CycleError{Path: cycle}
 		}
 	}
 
-	return fmt.Errorf("maximum resolution passes exceeded, possible unresolvable variable references")
+	return &CycleError{}
 }
 
 // collectDependencies builds a map of variable dependencies
 func collectDependencies(data map[string]interface{}, prefix string, deps map[string][]string) {
 	for key, value := range data {
-		fullKey := key
-		if prefix != "" {
-			fullKey = prefix + "." + key
-		}
-
-		switch v := value.(type) {
-		case string:
-			// Find all variable references in this string
-			matches := variablePattern.FindAllStringSubmatch(v, -1)
-			for _, match := range matches {
-				if len(match) == 2 {
-					referencedVar := match[1]
-					deps[fullKey] = append(deps[fullKey], referencedVar)
-				}
+		collectValueDependencies(value, joinPath(prefix, key), deps)
+	}
+}
+
+// collectValueDependencies records the references held by a single value, descending into tables and arrays
+func collectValueDependencies(value interface{}, fullKey string, deps map[string][]string) {
+	switch v := value.(type) {
+	case string:
+		// Find all variable references in this string
+		matches := variablePattern.FindAllStringSubmatch(v, -1)
+		for _, match := range matches {
+			if len(match) == 2 && !strings.HasPrefix(match[0], `\`) {
+				referencedVar, _, _ := parsePipeline(match[1])
+				deps[fullKey] = append(deps[fullKey], referencedVar)
 			}
-		case map[string]interface{}:
-			collectDependencies(v, fullKey, deps)
+		}
+	case map[string]interface{}:
+		collectDependencies(v, fullKey, deps)
+	case []interface{}:
+		for i, item := range v {
+			collectValueDependencies(item, fullKey+"."+strconv.Itoa(i), deps)
+		}
+	case []map[string]interface{}:
+		for i, item := range v {
+			collectDependencies(item, fullKey+"."+strconv.Itoa(i), deps)
 		}
 	}
 }
@@ -274,32 +610,32 @@ func findCycle(start string, graph map[string][]string) []string {
 	return path
 }
 
-// getAvailableVariablesList returns a formatted list of available variables
-func getAvailableVariablesList(data map[string]interface{}) string {
-	var keys []string
-	collectKeys(data, "", &keys)
-
-	if len(keys) == 0 {
-		return "- (no variables found)"
-	}
-
-	result := ""
-	for _, key := range keys {
-		result += "- " + key + "\n"
-	}
-	return strings.TrimSuffix(result, "\n")
-}
-
 // hasUnresolvedVariables checks if any string values still contain {{}} patterns
 func hasUnresolvedVariables(data map[string]interface{}) bool {
 	for _, value := range data {
-		switch v := value.(type) {
-		case string:
-			if strings.Contains(v, "{{") {
+		if valueHasUnresolvedVariables(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// valueHasUnresolvedVariables checks a single value, descending into tables and arrays
+func valueHasUnresolvedVariables(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return hasReferences(v)
+	case map[string]interface{}:
+		return hasUnresolvedVariables(v)
+	case []interface{}:
+		for _, item := range v {
+			if valueHasUnresolvedVariables(item) {
 				return true
 			}
-		case map[string]interface{}:
-			if hasUnresolvedVariables(v) {
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			if hasUnresolvedVariables(item) {
 				return true
 			}
 		}
@@ -309,16 +645,33 @@ func hasUnresolvedVariables(data map[string]interface{}) bool {
 
 // deepCopyMap creates a deep copy of a map[string]interface{}
 func deepCopyMap(original map[string]interface{}) map[string]interface{} {
-	copy := make(map[string]interface{})
+	copy := make(map[string]interface{}, len(original))
 
 	for key, value := range original {
-		switch v := value.(type) {
-		case map[string]interface{}:
-			copy[key] = deepCopyMap(v)
-		default:
-			copy[key] = v
-		}
+		copy[key] = deepCopyValue(value)
 	}
 
 	return copy
 }
+
+// deepCopyValue copies tables and arrays so resolution never mutates the loaded data
+func deepCopyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return deepCopyMap(v)
+	case []interface{}:
+		copy := make([]interface{}, len(v))
+		for i, item := range v {
+			copy[i] = deepCopyValue(item)
+		}
+		return copy
+	case []map[string]interface{}:
+		copy := make([]map[string]interface{}, len(v))
+		for i, item := range v {
+			copy[i] = deepCopyMap(item)
+		}
+		return copy
+	default:
+		return v
+	}
+}
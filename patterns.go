@@ -0,0 +1,95 @@
+package tomv
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFileName lists exclude patterns, one per line, in the search root
+const ignoreFileName = ".tomvignore"
+
+// readIgnoreFile returns the patterns in root/.tomvignore, skipping blank lines and # comments
+func readIgnoreFile(root string) ([]string, error) {
+	file, err := os.Open(filepath.Join(root, ignoreFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// matchesAny reports whether the root-relative path matches any pattern
+func matchesAny(patterns []string, relPath string, isDir bool) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, relPath, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a root-relative, slash-separated path against a glob pattern
+//
+// Patterns follow .gitignore conventions: a pattern without a slash matches the
+// name at any depth, "**" matches any number of directories, and a trailing
+// slash only matches directories.
+func matchGlob(pattern, relPath string, isDir bool) bool {
+	pattern = filepath.ToSlash(pattern)
+	relPath = filepath.ToSlash(relPath)
+
+	if strings.HasSuffix(pattern, "/") {
+		if !isDir {
+			return false
+		}
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(relPath))
+		return matched
+	}
+
+	pattern = strings.TrimPrefix(pattern, "/")
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(relPath, "/"))
+}
+
+// matchSegments matches path segments against pattern segments, where "**" spans zero or more segments
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try every possible number of segments for **
+			for skip := 0; skip <= len(parts); skip++ {
+				if matchSegments(pattern[1:], parts[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(parts) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], parts[0]); !matched {
+			return false
+		}
+		pattern = pattern[1:]
+		parts = parts[1:]
+	}
+	return len(parts) == 0
+}
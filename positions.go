@@ -0,0 +1,240 @@
+package tomv
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Position locates a key in a TOML file
+type Position struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`   // Starting at 1
+	Column int    `json:"column"` // Starting at 1
+}
+
+// String formats the position as file:line:column
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// OriginKind tells how a value was produced
+type OriginKind string
+
+const (
+	OriginLiteral   OriginKind = "literal"   // Written as is
+	OriginReference OriginKind = "reference" // Built from {{...}} references to other keys or sources
+	OriginEnv       OriginKind = "env"       // Built only from {{ENV...}} references
+)
+
+// ValueOrigin tells where a key is defined and how its value was produced
+type ValueOrigin struct {
+	Position
+	Kind       OriginKind `json:"kind"`
+	References []string   `json:"references,omitempty"` // Variables, sources and ENV.NAME the value refers to, sorted
+}
+
+// Origin returns where a key is defined, an overlay's position when one overrides the base file
+func (c *Config) Origin(key string) (ValueOrigin, error) {
+	return c.loadSnapshot().origin(key)
+}
+
+// origin locates a key in the snapshot and classifies its value as written
+func (s *snapshot) origin(key string) (ValueOrigin, error) {
+	if s.err != nil {
+		return ValueOrigin{}, s.err
+	}
+	fileData, localKey, err := locateKey(s.files, key, s.explicitPrefixes)
+	if err != nil {
+		return ValueOrigin{}, err
+	}
+
+	origin := ValueOrigin{Kind: OriginLiteral}
+	origin.Position, _ = fileData.position(localKey)
+	if origin.File == "" {
+		origin.File = fileData.Path
+	}
+
+	raw, _ := resolveKey(fileData.Data, localKey)
+	deps := make(map[string][]string)
+	collectValueDependencies(raw, localKey, deps)
+
+	seen := make(map[string]bool)
+	onlyEnv := true
+	for _, refs := range deps {
+		for _, ref := range refs {
+			if match := envPattern.FindStringSubmatch(ref); match != nil {
+				ref = "ENV." + match[1]
+			} else {
+				onlyEnv = false
+			}
+			if !seen[ref] {
+				seen[ref] = true
+				origin.References = append(origin.References, ref)
+			}
+		}
+	}
+	sort.Strings(origin.References)
+
+	switch {
+	case len(origin.References) == 0:
+	case onlyEnv:
+		origin.Kind = OriginEnv
+	default:
+		origin.Kind = OriginReference
+	}
+	return origin, nil
+}
+
+// Origin returns where a key of the default Config is defined
+func Origin(key string) (ValueOrigin, error) {
+	return defaultConfig.Origin(key)
+}
+
+// scannedKey is a key definition or table header found by scanKeys
+type scannedKey struct {
+	key    string
+	pos    Position
+	secret bool // Marked with a trailing # tomv:secret comment
+}
+
+// maxScanLine bounds the lines scanKeys reads, long enough for inline certificates and keys
+const maxScanLine = 16 << 20
+
+// scanKeys finds where each key and table of a TOML file is defined
+//
+// This is a line scanner, not a parser: it follows [table] and [[array]]
+// headers and key = value lines, skipping the continuation lines of
+// multi-line arrays, inline tables and strings, which is enough to point at
+// definitions. Keys inside inline tables resolve to the enclosing key.
+func scanKeys(path string) ([]scannedKey, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var keys []scannedKey
+	table := ""
+	arrayCounts := make(map[string]int)
+	depth := 0      // Open brackets and braces of the value being skipped
+	multiline := "" // Delimiter of the multi-line string being skipped
+	lineNumber := 0
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxScanLine)
+	for scanner.Scan() {
+		lineNumber++
+		raw := scanner.Text()
+		if depth > 0 || multiline != "" {
+			depth, multiline = scanValue(raw, depth, multiline)
+			continue
+		}
+
+		line := strings.TrimSpace(raw)
+		column := len(raw) - len(strings.TrimLeft(raw, " \t")) + 1
+		pos := Position{File: path, Line: lineNumber, Column: column}
+		marked := secretAnnotation.MatchString(line)
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[["):
+			name := keyPath(line[2:max(indexOutsideQuotes(line, ']'), 2)])
+			table = name + "." + strconv.Itoa(arrayCounts[name])
+			arrayCounts[name]++
+			keys = append(keys, scannedKey{key: table, pos: pos, secret: marked})
+		case strings.HasPrefix(line, "["):
+			table = keyPath(line[1:max(indexOutsideQuotes(line, ']'), 1)])
+			keys = append(keys, scannedKey{key: table, pos: pos, secret: marked})
+		default:
+			separator := indexOutsideQuotes(line, '=')
+			if separator < 0 {
+				continue // Not a definition, the parser reports it
+			}
+			keys = append(keys, scannedKey{key: joinPath(table, keyPath(line[:separator])), pos: pos, secret: marked})
+			depth, multiline = scanValue(line[separator+1:], 0, "")
+		}
+	}
+	return keys, scanner.Err()
+}
+
+// scanValue follows a value to the end of a line, returning what is still open
+//
+// Brackets and braces inside strings and comments don't count, and a
+// multi-line string carries over with its delimiter.
+func scanValue(text string, depth int, multiline string) (int, string) {
+	for i := 0; i < len(text); i++ {
+		if multiline != "" {
+			if text[i] == '\\' && multiline == `"""` {
+				i++ // Skip the escaped character
+			} else if strings.HasPrefix(text[i:], multiline) {
+				i += len(multiline) - 1
+				multiline = ""
+			}
+			continue
+		}
+
+		switch text[i] {
+		case '#':
+			return depth, ""
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth = max(depth-1, 0)
+		case '"', '\'':
+			if delimiter := strings.Repeat(text[i:i+1], 3); strings.HasPrefix(text[i:], delimiter) {
+				multiline = delimiter
+				i += len(delimiter) - 1
+				continue
+			}
+			end := closingQuote(text, i)
+			if end < 0 {
+				return depth, "" // Unterminated string, the parser reports it
+			}
+			i = end
+		}
+	}
+	return depth, multiline
+}
+
+// indexOutsideQuotes returns the index of the first c outside a quoted string, or -1
+func indexOutsideQuotes(text string, c byte) int {
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case c:
+			return i
+		case '"', '\'':
+			if i = closingQuote(text, i); i < 0 {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// closingQuote returns the index of the quote closing the string that starts at start, or -1
+func closingQuote(text string, start int) int {
+	quote := text[start]
+	for i := start + 1; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && quote == '"':
+			i++ // Skip the escaped character
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// keyPath normalizes a possibly quoted dotted key such as server."api host" to server.api host
+func keyPath(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,444 @@
+package tomv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// schemaFileName holds the schema in the search root, it is never loaded as configuration
+const schemaFileName = "tomv.schema.toml"
+
+// UnknownKeyPolicy decides what Validate does with keys the schema doesn't mention
+type UnknownKeyPolicy int
+
+const (
+	// AllowUnknownKeys ignores keys without a rule (the default)
+	AllowUnknownKeys UnknownKeyPolicy = iota
+	// RejectUnknownKeys reports every key not covered by a rule
+	RejectUnknownKeys
+)
+
+// Schema describes the expected shape of the resolved configuration
+//
+// Keys are addressed like Get: "server.port", "app.server.port" or
+// "app:server.port" for one file, or glob patterns such as "upstreams.*.url".
+// A rule on a table also covers every key inside it for the unknown-key
+// policy. A rule on an unprefixed key applies to every file defining it.
+type Schema struct {
+	Keys    map[string]Rule  `json:"keys"`
+	Unknown UnknownKeyPolicy `json:"unknown"`
+}
+
+// Rule constrains the value of one key, zero fields are not checked
+type Rule struct {
+	Type     string      `json:"type,omitempty"` // string, int, float, bool, duration, datetime, array or table
+	Required bool        `json:"required,omitempty"`
+	Enum     []string    `json:"enum,omitempty"`    // Allowed values, compared as Get returns them
+	Min      interface{} `json:"min,omitempty"`     // Number, or time.Duration / duration string for durations
+	Max      interface{} `json:"max,omitempty"`     // Number, or time.Duration / duration string for durations
+	Pattern  string      `json:"pattern,omitempty"` // Regular expression the value must match, unanchored
+}
+
+// LoadSchema reads a schema from a TOML file
+//
+// Top-level quoted tables hold the rules, a top-level unknown_keys setting
+// ("allow" or "reject") holds the policy:
+//
+//	unknown_keys = "reject"
+//
+//	["server.port"]
+//	type = "int"
+//	required = true
+//	min = 1
+//	max = 65535
+func LoadSchema(path string) (*Schema, error) {
+	var raw map[string]interface{}
+	if _, err := toml.DecodeFile(path, &raw); err != nil {
+		return nil, newError(ErrParse, "failed to parse schema %s: %v", path, err)
+	}
+
+	schema := &Schema{Keys: make(map[string]Rule)}
+	for key, value := range raw {
+		if key == "unknown_keys" {
+			switch value {
+			case "allow":
+				schema.Unknown = AllowUnknownKeys
+			case "reject":
+				schema.Unknown = RejectUnknownKeys
+			default:
+				return nil, fmt.Errorf("schema %s: unknown_keys must be \"allow\" or \"reject\", got %v", path, value)
+			}
+			continue
+		}
+
+		table, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("schema %s: rule for \"%s\" must be a table", path, key)
+		}
+		rule, err := parseRule(table)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: rule for \"%s\": %v", path, key, err)
+		}
+		schema.Keys[key] = rule
+	}
+	return schema, nil
+}
+
+// parseRule builds a Rule from its TOML table
+func parseRule(table map[string]interface{}) (Rule, error) {
+	var rule Rule
+	for field, value := range table {
+		var ok bool
+		switch field {
+		case "type":
+			rule.Type, ok = value.(string)
+		case "required":
+			rule.Required, ok = value.(bool)
+		case "pattern":
+			rule.Pattern, ok = value.(string)
+		case "min":
+			rule.Min, ok = value, true
+		case "max":
+			rule.Max, ok = value, true
+		case "enum":
+			var items []interface{}
+			items, ok = value.([]interface{})
+			for _, item := range items {
+				rule.Enum = append(rule.Enum, formatValue(item))
+			}
+		default:
+			return Rule{}, fmt.Errorf("unknown field \"%s\"", field)
+		}
+		if !ok {
+			return Rule{}, fmt.Errorf("invalid %s: %v", field, value)
+		}
+	}
+	return rule, nil
+}
+
+// compiledRule is a Rule with its pattern and bounds parsed
+type compiledRule struct {
+	Rule
+	pattern  *regexp.Regexp
+	min, max *float64 // Nanoseconds for durations
+}
+
+// compile checks a rule for mistakes and parses its pattern and bounds
+func (r Rule) compile() (compiledRule, error) {
+	compiled := compiledRule{Rule: r}
+
+	switch r.Type {
+	case "", "string", "int", "float", "bool", "duration", "datetime", "array", "table":
+	default:
+		return compiledRule{}, fmt.Errorf("unknown type \"%s\"", r.Type)
+	}
+
+	if r.Pattern != "" {
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return compiledRule{}, fmt.Errorf("invalid pattern: %v", err)
+		}
+		compiled.pattern = pattern
+	}
+
+	var err error
+	if compiled.min, err = r.bound(r.Min); err != nil {
+		return compiledRule{}, fmt.Errorf("invalid min: %v", err)
+	}
+	if compiled.max, err = r.bound(r.Max); err != nil {
+		return compiledRule{}, fmt.Errorf("invalid max: %v", err)
+	}
+	return compiled, nil
+}
+
+// bound converts a Min or Max to a number, durations count in nanoseconds
+func (r Rule) bound(value interface{}) (*float64, error) {
+	var result float64
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case time.Duration:
+		result = float64(v)
+	case string:
+		if r.Type != "duration" {
+			return nil, fmt.Errorf("%q is only allowed for durations", v)
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		result = float64(d)
+	case int:
+		result = float64(v)
+	case int64:
+		result = float64(v)
+	case float64:
+		result = v
+	default:
+		return nil, fmt.Errorf("unsupported bound %v", value)
+	}
+	return &result, nil
+}
+
+// check returns what is wrong with a value, or an empty string if it satisfies the rule
+func (r compiledRule) check(value interface{}) string {
+	var number *float64
+	switch r.Type {
+	case "string":
+		switch value.(type) {
+		case map[string]interface{}, []map[string]interface{}, []interface{}:
+			return "expected a string"
+		}
+	case "int":
+		n, err := asInt("", value)
+		if err != nil {
+			return "expected an integer"
+		}
+		f := float64(n)
+		number = &f
+	case "float":
+		f, err := asFloat("", value)
+		if err != nil {
+			return "expected a float"
+		}
+		number = &f
+	case "bool":
+		if _, err := asBool("", value); err != nil {
+			return "expected a boolean"
+		}
+	case "duration":
+		d, err := asDuration("", value)
+		if err != nil {
+			return "expected a duration"
+		}
+		f := float64(d)
+		number = &f
+	case "datetime":
+		if _, err := asTime("", value); err != nil {
+			return "expected a datetime"
+		}
+	case "array":
+		switch value.(type) {
+		case []interface{}, []map[string]interface{}:
+		default:
+			return "expected an array"
+		}
+	case "table":
+		if _, isTable := value.(map[string]interface{}); !isTable {
+			return "expected a table"
+		}
+	default:
+		if f, err := asFloat("", value); err == nil {
+			number = &f
+		}
+	}
+
+	if number != nil {
+		if r.min != nil && *number < *r.min {
+			return "must be at least " + r.formatBound(*r.min)
+		}
+		if r.max != nil && *number > *r.max {
+			return "must be at most " + r.formatBound(*r.max)
+		}
+	}
+
+	str := formatValue(value)
+	if len(r.Enum) > 0 && !containsString(r.Enum, str) {
+		return "must be one of " + strings.Join(r.Enum, ", ")
+	}
+	if r.pattern != nil && !r.pattern.MatchString(str) {
+		return fmt.Sprintf("must match pattern %s", r.Pattern)
+	}
+	return ""
+}
+
+// formatBound renders a bound the way it was written
+func (r compiledRule) formatBound(bound float64) string {
+	if r.Type == "duration" {
+		return time.Duration(bound).String()
+	}
+	return fmt.Sprintf("%v", bound)
+}
+
+// containsString reports whether list holds str
+func containsString(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the resolved configuration against the schema set with WithSchema,
+// or against tomv.schema.toml in the search root if there is one
+//
+// Every violation is reported together in a *ValidationError. Without a
+// schema only loading and resolution errors are returned.
+func (c *Config) Validate() error {
+	snap := c.loadSnapshot()
+	if snap.err != nil {
+		return snap.err
+	}
+
+	schema := c.schema
+	if schema == nil {
+		root, err := c.findProjectRoot()
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(root, schemaFileName)
+		if _, err := os.Stat(path); err != nil {
+			return nil // No schema
+		}
+		if schema, err = LoadSchema(path); err != nil {
+			return err
+		}
+	}
+	return schema.validate(snap.files)
+}
+
+// validate checks every rule against the loaded files
+func (s *Schema) validate(files []FileData) error {
+	patterns := make([]string, 0, len(s.Keys))
+	rules := make(map[string]compiledRule, len(s.Keys))
+	for pattern, rule := range s.Keys {
+		compiled, err := rule.compile()
+		if err != nil {
+			return fmt.Errorf("invalid schema rule for \"%s\": %v", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+		rules[pattern] = compiled
+	}
+	sort.Strings(patterns)
+
+	fileKeys := make([][]string, len(files))
+	for i := range files {
+		collectKeys(files[i].Resolved, "", &fileKeys[i])
+		sort.Strings(fileKeys[i])
+	}
+
+	var violations []*Violation
+	report := func(fileData *FileData, key, displayKey, message string) {
+		violation := &Violation{Key: displayKey, Message: message}
+		if fileData != nil {
+			violation.Position, _ = fileData.position(key)
+		}
+		violations = append(violations, violation)
+	}
+
+	for _, pattern := range patterns {
+		rule := rules[pattern]
+		found := false
+
+		for i := range files {
+			fileData := &files[i]
+			for _, key := range schemaMatches(fileData, fileKeys[i], pattern) {
+				found = true
+				value, _ := resolveKey(fileData.Resolved, key)
+				if message := rule.check(value); message != "" {
+					got := formatValue(fileData.masked(key, value))
+					report(fileData, key, displayKey(fileData, key, pattern), fmt.Sprintf("%s, got %q", message, got))
+				}
+			}
+		}
+
+		if !found && rule.Required {
+			report(nil, "", pattern, "required key is missing")
+		}
+	}
+
+	if s.Unknown == RejectUnknownKeys {
+		for i := range files {
+			fileData := &files[i]
+			for _, key := range fileKeys[i] {
+				if !schemaCovers(patterns, fileData.Prefix, key) {
+					report(fileData, key, fileData.Prefix+"."+key, "unknown key")
+				}
+			}
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+// schemaMatches returns the keys of a file a schema key applies to
+func schemaMatches(fileData *FileData, keys []string, pattern string) []string {
+	if !strings.Contains(pattern, "*") {
+		// Exact keys may name tables and arrays as well as variables
+		var matches []string
+		for _, key := range []string{pattern, strings.TrimPrefix(pattern, fileData.Prefix+"."), strings.TrimPrefix(pattern, fileData.Prefix+explicitSeparator)} {
+			if _, found := resolveKey(fileData.Resolved, key); found && schemaMatch(pattern, fileData.Prefix, key) && !containsString(matches, key) {
+				matches = append(matches, key)
+			}
+		}
+		return matches
+	}
+
+	var matches []string
+	for _, key := range keys {
+		if schemaMatch(pattern, fileData.Prefix, key) {
+			matches = append(matches, key)
+		}
+	}
+	return matches
+}
+
+// schemaCovers reports whether a rule applies to a key or to a table containing it
+func schemaCovers(patterns []string, prefix, key string) bool {
+	for path := key; ; {
+		for _, pattern := range patterns {
+			if schemaMatch(pattern, prefix, path) {
+				return true
+			}
+		}
+		dot := strings.LastIndex(path, ".")
+		if dot < 0 {
+			return false
+		}
+		path = path[:dot]
+	}
+}
+
+// schemaMatch reports whether a schema key addresses key in the file with the given prefix
+//
+// The schema key is written the way Get reads it, with or without the file
+// prefix, and matches from the first segment. In globs * stays within one
+// segment and ** spans any number of them.
+func schemaMatch(pattern, prefix, key string) bool {
+	for _, candidate := range []string{key, prefix + "." + key, prefix + explicitSeparator + key} {
+		if candidate == pattern {
+			return true
+		}
+		if strings.Contains(pattern, "*") && matchSegments(strings.Split(pattern, "."), strings.Split(candidate, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// displayKey names a matched key the way the schema addressed it
+func displayKey(fileData *FileData, key, pattern string) string {
+	if strings.HasPrefix(pattern, fileData.Prefix+".") {
+		return fileData.Prefix + "." + key
+	}
+	if strings.HasPrefix(pattern, fileData.Prefix+explicitSeparator) {
+		return fileData.Prefix + explicitSeparator + key
+	}
+	return key
+}
+
+// Validate checks the default Config against its schema
+func Validate() error {
+	return defaultConfig.Validate()
+}
@@ -0,0 +1,124 @@
+package tomv
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// redactedValue replaces secret values wherever they could be shown
+const redactedValue = "****"
+
+// secretAnnotation marks a key, or a whole table when placed on its header, as secret
+var secretAnnotation = regexp.MustCompile(`#\s*tomv:secret\s*$`)
+
+// Secret holds a sensitive value that prints as **** in every format, log and JSON output
+type Secret struct {
+	value string
+}
+
+// Reveal returns the secret value
+func (s Secret) Reveal() string {
+	return s.value
+}
+
+// String returns ****
+func (s Secret) String() string {
+	return redactedValue
+}
+
+// GoString returns a masked form for %#v
+func (s Secret) GoString() string {
+	return "tomv.Secret(" + redactedValue + ")"
+}
+
+// Format prints **** for every verb
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		fmt.Fprint(f, s.GoString())
+		return
+	}
+	fmt.Fprint(f, redactedValue)
+}
+
+// MarshalText returns ****, so JSON and text encoders never see the value
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(redactedValue), nil
+}
+
+// UnmarshalText stores text as the secret value, letting Bind fill Secret fields
+func (s *Secret) UnmarshalText(text []byte) error {
+	s.value = string(text)
+	return nil
+}
+
+// maskMismatch shows masked in place of the value of a conversion error
+func maskMismatch(err error, masked interface{}) error {
+	var mismatch *mismatchError
+	if errors.As(err, &mismatch) {
+		mismatch.value = masked
+	}
+	return err
+}
+
+// matchesKeyPattern reports whether a dotted key matches a glob pattern such as "password" or "db.*"
+func matchesKeyPattern(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(strings.ReplaceAll(pattern, ".", "/"), strings.ReplaceAll(key, ".", "/"), false) {
+			return true
+		}
+	}
+	return false
+}
+
+// findSecretKeys returns the file-prefixed keys holding secrets, starting from the marked ones
+//
+// Values read from redacted sources are secret too. Secrecy follows references,
+// so a key interpolating a secret, or copying a table holding one, is secret
+// as well.
+func findSecretKeys(data map[string]interface{}, marked map[string]bool, explicitPrefixes bool) map[string]bool {
+	dependencies := make(map[string][]string)
+	collectDependencies(data, "", dependencies)
+
+	secrets := make(map[string]bool, len(marked))
+	for key := range marked {
+		secrets[key] = true
+	}
+	for variable, refs := range dependencies {
+		for _, ref := range refs {
+			if s, _, ok := findSource(ref); ok && s.redact {
+				if _, internal, _ := qualifyReference(ref, data, variable, explicitPrefixes); !internal {
+					secrets[variable] = true
+				}
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for variable, refs := range dependencies {
+			if secrets[variable] {
+				continue
+			}
+			for _, ref := range refs {
+				if qualified, found, _ := qualifyReference(ref, data, variable, explicitPrefixes); found && coversSecret(secrets, qualified) {
+					secrets[variable] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return secrets
+}
+
+// coversSecret reports whether key, one of its parents or one of its children is secret
+func coversSecret(secrets map[string]bool, key string) bool {
+	for secretKey := range secrets {
+		if secretKey == key || strings.HasPrefix(secretKey, key+".") || strings.HasPrefix(key, secretKey+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package tomv
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// View is an immutable, fully resolved configuration returned by Snapshot
+//
+// Every read from a View sees the same generation of files, even while they
+// are being rewritten, so related keys such as db.host and db.port stay consistent.
+type View struct {
+	snap *snapshot
+}
+
+// Snapshot returns the current configuration as an immutable View
+func (c *Config) Snapshot() *View {
+	return &View{snap: c.loadSnapshot()}
+}
+
+// Generation identifies the load the View was taken from, it increases every time files are reloaded
+func (v *View) Generation() uint64 {
+	return v.snap.generation
+}
+
+// Err returns the discovery or resolution error shared by every key of the View, if any
+func (v *View) Err() error {
+	return v.snap.err
+}
+
+// Diagnostics lists the files left out of the View's load
+func (v *View) Diagnostics() []*Diagnostic {
+	return v.snap.diagnostics
+}
+
+// Get retrieves a string value by key, panics if not found
+func (v *View) Get(key string) string {
+	return getAs(v.snap.lookup, key, asString)
+}
+
+// GetInt retrieves an integer value by key, panics if not found or invalid
+func (v *View) GetInt(key string) int {
+	return getAs(v.snap.lookup, key, asInt)
+}
+
+// GetBool retrieves a boolean value by key, panics if not found or invalid
+func (v *View) GetBool(key string) bool {
+	return getAs(v.snap.lookup, key, asBool)
+}
+
+// GetFloat retrieves a float64 value by key, panics if not found or invalid
+func (v *View) GetFloat(key string) float64 {
+	return getAs(v.snap.lookup, key, asFloat)
+}
+
+// GetDuration retrieves a time.Duration value by key, panics if not found or invalid
+func (v *View) GetDuration(key string) time.Duration {
+	return getAs(v.snap.lookup, key, asDuration)
+}
+
+// GetTime retrieves a TOML datetime value by key, panics if not found or invalid
+func (v *View) GetTime(key string) time.Time {
+	return getAs(v.snap.lookup, key, asTime)
+}
+
+// GetStringSlice retrieves a TOML array or comma-separated string as a slice, panics if not found
+func (v *View) GetStringSlice(key string) []string {
+	return getAs(v.snap.lookup, key, asStringSlice)
+}
+
+// GetIntSlice retrieves a TOML integer array or comma-separated string as an int slice, panics if not found or invalid
+func (v *View) GetIntSlice(key string) []int {
+	return getAs(v.snap.lookup, key, asIntSlice)
+}
+
+// GetSecret retrieves a value by key as a Secret that prints as ****, panics if not found
+func (v *View) GetSecret(key string) Secret {
+	return getAs(v.snap.lookup, key, asSecret)
+}
+
+// GetOr retrieves a string value by key, returns default if not found
+func (v *View) GetOr(key string, defaultValue string) string {
+	return getAsOr(v.snap.lookup, key, asString, defaultValue)
+}
+
+// GetIntOr retrieves an integer value by key, returns default if not found or invalid
+func (v *View) GetIntOr(key string, defaultValue int) int {
+	return getAsOr(v.snap.lookup, key, asInt, defaultValue)
+}
+
+// GetBoolOr retrieves a boolean value by key, returns default if not found or invalid
+func (v *View) GetBoolOr(key string, defaultValue bool) bool {
+	return getAsOr(v.snap.lookup, key, asBool, defaultValue)
+}
+
+// GetFloatOr retrieves a float64 value by key, returns default if not found or invalid
+func (v *View) GetFloatOr(key string, defaultValue float64) float64 {
+	return getAsOr(v.snap.lookup, key, asFloat, defaultValue)
+}
+
+// GetDurationOr retrieves a time.Duration value by key, returns default if not found or invalid
+func (v *View) GetDurationOr(key string, defaultValue time.Duration) time.Duration {
+	return getAsOr(v.snap.lookup, key, asDuration, defaultValue)
+}
+
+// GetTimeOr retrieves a TOML datetime value by key, returns default if not found or invalid
+func (v *View) GetTimeOr(key string, defaultValue time.Time) time.Time {
+	return getAsOr(v.snap.lookup, key, asTime, defaultValue)
+}
+
+// GetStringSliceOr retrieves a TOML array or comma-separated string as a slice, returns default if not found
+func (v *View) GetStringSliceOr(key string, defaultValue []string) []string {
+	return getAsOr(v.snap.lookup, key, asStringSlice, defaultValue)
+}
+
+// GetIntSliceOr retrieves a TOML integer array or comma-separated string as an int slice, returns default if not found or invalid
+func (v *View) GetIntSliceOr(key string, defaultValue []int) []int {
+	return getAsOr(v.snap.lookup, key, asIntSlice, defaultValue)
+}
+
+// Lookup retrieves a string value by key, returns an error if not found
+func (v *View) Lookup(key string) (string, error) {
+	return lookupAs(v.snap.lookup, key, asString)
+}
+
+// LookupInt retrieves an integer value by key, returns an error if not found or invalid
+func (v *View) LookupInt(key string) (int, error) {
+	return lookupAs(v.snap.lookup, key, asInt)
+}
+
+// LookupBool retrieves a boolean value by key, returns an error if not found or invalid
+func (v *View) LookupBool(key string) (bool, error) {
+	return lookupAs(v.snap.lookup, key, asBool)
+}
+
+// LookupFloat retrieves a float64 value by key, returns an error if not found or invalid
+func (v *View) LookupFloat(key string) (float64, error) {
+	return lookupAs(v.snap.lookup, key, asFloat)
+}
+
+// LookupDuration retrieves a time.Duration value by key, returns an error if not found or invalid
+func (v *View) LookupDuration(key string) (time.Duration, error) {
+	return lookupAs(v.snap.lookup, key, asDuration)
+}
+
+// LookupTime retrieves a TOML datetime value by key, returns an error if not found or invalid
+func (v *View) LookupTime(key string) (time.Time, error) {
+	return lookupAs(v.snap.lookup, key, asTime)
+}
+
+// LookupStringSlice retrieves a TOML array or comma-separated string as a slice, returns an error if not found
+func (v *View) LookupStringSlice(key string) ([]string, error) {
+	return lookupAs(v.snap.lookup, key, asStringSlice)
+}
+
+// LookupIntSlice retrieves a TOML integer array or comma-separated string as an int slice, returns an error if not found or invalid
+func (v *View) LookupIntSlice(key string) ([]int, error) {
+	return lookupAs(v.snap.lookup, key, asIntSlice)
+}
+
+// LookupSecret retrieves a value by key as a Secret that prints as ****, returns an error if not found
+func (v *View) LookupSecret(key string) (Secret, error) {
+	return lookupAs(v.snap.lookup, key, asSecret)
+}
+
+// Exists checks if a variable exists without retrieving its value
+func (v *View) Exists(key string) bool {
+	return v.snap.lookup(key).err == nil
+}
+
+// Origin returns where a key of the View is defined
+func (v *View) Origin(key string) (ValueOrigin, error) {
+	return v.snap.origin(key)
+}
+
+// Bind fills a struct from the View using `tomv` field tags
+func (v *View) Bind(target interface{}) error {
+	return v.Unmarshal("", target)
+}
+
+// Unmarshal fills a struct from the View, with tag keys relative to prefix
+func (v *View) Unmarshal(prefix string, target interface{}) error {
+	return unmarshalSnapshot(v.snap, prefix, target)
+}
+
+// String dumps every variable as key = value, one per line, with secrets masked
+func (v *View) String() string {
+	values := flattenSnapshot(v.snap)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var dump strings.Builder
+	for _, key := range keys {
+		dump.WriteString(key + " = " + formatValue(values[key]) + "\n")
+	}
+	return dump.String()
+}
+
+// Snapshot returns the default Config's current configuration as an immutable View
+func Snapshot() *View {
+	return defaultConfig.Snapshot()
+}
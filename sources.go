@@ -0,0 +1,235 @@
+package tomv
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source resolves {{name.key}} references for the name it is registered under
+type Source interface {
+	// Lookup returns the value for key, found is false when the source has no such key
+	Lookup(key string) (value string, found bool, err error)
+}
+
+// SourceFunc adapts a function to the Source interface
+type SourceFunc func(key string) (string, bool, error)
+
+// Lookup calls f(key)
+func (f SourceFunc) Lookup(key string) (string, bool, error) {
+	return f(key)
+}
+
+// SourceOption configures a registered Source
+type SourceOption func(*registeredSource)
+
+// CacheFor reuses looked up values for d instead of asking the source on every reload
+func CacheFor(d time.Duration) SourceOption {
+	return func(s *registeredSource) {
+		s.ttl = d
+	}
+}
+
+// Redacted treats values from this source, and values derived from them, as secrets
+func Redacted() SourceOption {
+	return func(s *registeredSource) {
+		s.redact = true
+	}
+}
+
+// registeredSource is a Source with its caching and redaction rules
+type registeredSource struct {
+	source Source
+	ttl    time.Duration
+	redact bool
+
+	mutex   sync.Mutex
+	entries map[string]sourceEntry
+}
+
+type sourceEntry struct {
+	value   string
+	found   bool
+	expires time.Time
+}
+
+// lookup asks the source for key within a load, answering from the cache while the entry is fresh
+func (s *registeredSource) lookup(load *sourceLoad, key string) (string, bool, error) {
+	if s.ttl <= 0 {
+		return load.ask(s.source, key)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entry, exists := s.entries[key]; exists && time.Now().Before(entry.expires) {
+		return entry.value, entry.found, nil
+	}
+	value, found, err := load.ask(s.source, key)
+	if err != nil {
+		return "", false, err // Errors are retried on the next reload
+	}
+	s.entries[key] = sourceEntry{value: value, found: found, expires: time.Now().Add(s.ttl)}
+	return value, found, nil
+}
+
+var (
+	sourcesMutex sync.RWMutex
+	sources      = map[string]*registeredSource{
+		"file":   {source: FileSource(), redact: true},
+		"dotenv": {source: DotenvSource(".env")},
+	}
+)
+
+// RegisterSource makes {{name.key}} references resolve through src, replacing any source with that name
+//
+// A reference only reaches the source when no TOML key answers it, so a
+// [file] table still shadows the built-in file source. Lookups are repeated
+// on every reload unless the source is registered with CacheFor.
+func RegisterSource(name string, src Source, opts ...SourceOption) {
+	s := &registeredSource{source: src, entries: make(map[string]sourceEntry)}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	sourcesMutex.Lock()
+	defer sourcesMutex.Unlock()
+
+	sources[name] = s
+}
+
+// unregisterSource removes the source registered under name (useful for testing)
+func unregisterSource(name string) {
+	sourcesMutex.Lock()
+	defer sourcesMutex.Unlock()
+
+	delete(sources, name)
+}
+
+// findSource splits a reference into a registered source and its key
+func findSource(ref string) (*registeredSource, string, bool) {
+	name, key, ok := strings.Cut(ref, ".")
+	if !ok {
+		return nil, "", false
+	}
+
+	sourcesMutex.RLock()
+	defer sourcesMutex.RUnlock()
+
+	s, exists := sources[name]
+	return s, key, exists
+}
+
+// FileSource reads a whole file, e.g. {{file./run/secrets/db_password}}, without its trailing newline
+func FileSource() Source {
+	return SourceFunc(func(path string) (string, bool, error) {
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return strings.TrimRight(string(content), "\r\n"), true, nil
+	})
+}
+
+// sourceLoad is the state sources share during one load of the configuration
+type sourceLoad struct {
+	root    string                 // Config root, relative source paths are resolved against it
+	dotenvs map[string]dotenvEntry // .env files parsed so far, by path
+}
+
+type dotenvEntry struct {
+	values map[string]string
+	err    error
+}
+
+func newSourceLoad(root string) *sourceLoad {
+	return &sourceLoad{root: root, dotenvs: make(map[string]dotenvEntry)}
+}
+
+// ask looks key up in source, reading .env files relative to the root and only once per load
+func (l *sourceLoad) ask(source Source, key string) (string, bool, error) {
+	dotenv, ok := source.(dotenvSource)
+	if !ok {
+		return source.Lookup(key)
+	}
+
+	path := dotenv.path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(l.root, path)
+	}
+	entry, parsed := l.dotenvs[path]
+	if !parsed {
+		entry.values, entry.err = readDotenv(path)
+		l.dotenvs[path] = entry
+	}
+	if entry.err != nil {
+		return "", false, entry.err
+	}
+	value, found := entry.values[key]
+	return value, found, nil
+}
+
+// dotenvSource is the Source returned by DotenvSource
+type dotenvSource struct {
+	path string
+}
+
+// DotenvSource reads KEY=value lines from a .env file, e.g. {{dotenv.API_KEY}}
+//
+// Once registered, relative paths are resolved against the Config root and
+// the file is read once per reload. A missing file has no keys.
+func DotenvSource(path string) Source {
+	return dotenvSource{path: path}
+}
+
+// Lookup reads key from the file, resolving a relative path against the working directory
+func (s dotenvSource) Lookup(key string) (string, bool, error) {
+	values, err := readDotenv(s.path)
+	if err != nil {
+		return "", false, err
+	}
+	value, found := values[key]
+	return value, found, nil
+}
+
+// readDotenv parses a .env file, skipping blank lines, # comments and export prefixes
+func readDotenv(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+			value = unquoted
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values, scanner.Err()
+}
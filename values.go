@@ -0,0 +1,192 @@
+package tomv
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TOML local date/time values decode to time.Time in these zones (see BurntSushi/toml internal/tz.go)
+const (
+	localDatetimeZone = "datetime-local"
+	localDateZone     = "date-local"
+	localTimeZone     = "time-local"
+)
+
+// formatValue renders a native TOML value as the string returned by Get and used in {{variable}} substitution
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return formatTime(v)
+	case []interface{}:
+		// Arrays use the same comma-separated form that GetStringSlice splits
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = formatValue(item)
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// formatTime renders a TOML datetime in the same layout it was written in
+func formatTime(t time.Time) string {
+	switch t.Location().String() {
+	case localDatetimeZone:
+		return t.Format("2006-01-02T15:04:05.999999999")
+	case localDateZone:
+		return t.Format("2006-01-02")
+	case localTimeZone:
+		return t.Format("15:04:05.999999999")
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
+
+// asString renders any value as the string returned by Get
+func asString(key string, value interface{}) (string, error) {
+	return formatValue(value), nil
+}
+
+// asSecret wraps the string form of a value in a Secret
+func asSecret(key string, value interface{}) (Secret, error) {
+	return Secret{value: formatValue(value)}, nil
+}
+
+// asInt converts a TOML integer, a whole float such as 3000.0, or a string holding an integer, to int
+func asInt(key string, value interface{}) (int, error) {
+	switch v := value.(type) {
+	case int64:
+		return int(v), nil
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int(v), nil
+		}
+	case string:
+		result, err := strconv.Atoi(v)
+		if err == nil {
+			return result, nil
+		}
+	}
+	return 0, newMismatch("variable \"%s\" is not a valid integer: %s", key, value)
+}
+
+// asBool converts a TOML boolean, the integers 0 and 1, or a string holding one, to bool
+func asBool(key string, value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case int64:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	case string:
+		if result, ok := parseBool(v); ok {
+			return result, nil
+		}
+	}
+	return false, newMismatch("variable \"%s\" is not a valid boolean: %s", key, value)
+}
+
+// asFloat converts a TOML float or integer, or a string holding one, to float64
+func asFloat(key string, value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case string:
+		result, err := strconv.ParseFloat(v, 64)
+		if err == nil {
+			return result, nil
+		}
+	}
+	return 0, newMismatch("variable \"%s\" is not a valid float: %s", key, value)
+}
+
+// asDuration converts a Go duration string to time.Duration
+func asDuration(key string, value interface{}) (time.Duration, error) {
+	if v, ok := value.(string); ok {
+		result, err := time.ParseDuration(v)
+		if err == nil {
+			return result, nil
+		}
+	}
+	return 0, newMismatch("variable \"%s\" is not a valid duration: %s", key, value)
+}
+
+// asTime converts a TOML datetime, or an RFC 3339 / local datetime string, to time.Time
+func asTime(key string, value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		layouts := []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02"}
+		for _, layout := range layouts {
+			if result, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+				return result, nil
+			}
+		}
+	}
+	return time.Time{}, newMismatch("variable \"%s\" is not a valid datetime: %s", key, value)
+}
+
+// asStringSlice converts a TOML array to strings, falling back to splitting comma-separated strings
+func asStringSlice(key string, value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		result := make([]string, len(v))
+		for i, item := range v {
+			if _, isTable := item.(map[string]interface{}); isTable {
+				return nil, newError(ErrTypeMismatch, "variable \"%s\" contains a table, not a string", key)
+			}
+			result[i] = formatValue(item)
+		}
+		return result, nil
+	case []map[string]interface{}, map[string]interface{}:
+		return nil, newError(ErrTypeMismatch, "variable \"%s\" is a table, not a list", key)
+	default:
+		return splitList(formatValue(value)), nil
+	}
+}
+
+// asIntSlice converts a TOML integer array to ints, falling back to splitting comma-separated strings
+func asIntSlice(key string, value interface{}) ([]int, error) {
+	items, isArray := value.([]interface{})
+	if !isArray {
+		stringSlice, err := asStringSlice(key, value)
+		if err != nil {
+			return nil, err
+		}
+		items = make([]interface{}, len(stringSlice))
+		for i, str := range stringSlice {
+			items[i] = str
+		}
+	}
+
+	result := make([]int, len(items))
+	for i, item := range items {
+		switch v := item.(type) {
+		case int64:
+			result[i] = int(v)
+		case string:
+			str := strings.TrimSpace(v)
+			if str == "" {
+				continue // Skip empty strings
+			}
+			num, err := strconv.Atoi(str)
+			if err != nil {
+				return nil, newMismatch("variable \"%s\" contains invalid integer: %s", key, str)
+			}
+			result[i] = num
+		default:
+			return nil, newMismatch("variable \"%s\" contains invalid integer: %s", key, item)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,351 @@
+package tomv
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultPollInterval is used when native file notifications are unavailable
+const defaultPollInterval = time.Second
+
+// watchDebounce groups the burst of events a single save produces into one reload
+const watchDebounce = 50 * time.Millisecond
+
+// KeyChange describes one variable that changed between two snapshots
+type KeyChange struct {
+	Key      string      `json:"key"`       // Key as accepted by Get, file-prefixed when it exists in several files
+	OldValue interface{} `json:"old_value"` // nil when the key was added
+	NewValue interface{} `json:"new_value"` // nil when the key was removed
+}
+
+// ChangeEvent is delivered to OnChange callbacks after the watcher rebuilds the configuration
+type ChangeEvent struct {
+	Generation uint64      `json:"generation"`
+	Changes    []KeyChange `json:"changes"`
+	Err        error       `json:"-"` // Set when the new files failed to load or resolve
+}
+
+// fileWatcher notifies about changes in a set of directories
+type fileWatcher interface {
+	// watch replaces the watched directory set
+	watch(dirs []string) error
+	close() error
+}
+
+// watchState holds the running watcher and registered callbacks of a Config
+type watchState struct {
+	mutex     sync.Mutex
+	listeners []func(ChangeEvent)
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// OnChange registers a callback invoked from the watcher goroutine whenever watched files change
+//
+// Callbacks run one at a time and hold up the next reload while they run.
+// Close waits for the watcher goroutine, so a callback must not call it
+// directly; use go c.Close() instead.
+func (c *Config) OnChange(fn func(ChangeEvent)) {
+	c.watcher.mutex.Lock()
+	defer c.watcher.mutex.Unlock()
+
+	c.watcher.listeners = append(c.watcher.listeners, fn)
+}
+
+// Watch starts rebuilding the configuration in the background when TOML files change
+//
+// Native notifications (inotify) are used where available, falling back to
+// polling. While watching, reads trust the current snapshot instead of
+// checking files themselves.
+func (c *Config) Watch() error {
+	c.watcher.mutex.Lock()
+	defer c.watcher.mutex.Unlock()
+
+	if c.watcher.stop != nil {
+		return nil // Already watching
+	}
+
+	events := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case events <- struct{}{}:
+		default: // A reload is already pending
+		}
+	}
+
+	var fw fileWatcher
+	forceReload := false
+	if c.pollInterval <= 0 {
+		if native, err := newFileWatcher(notify); err == nil {
+			fw = native
+			forceReload = true // Events are definite, don't second-guess them with timestamps
+		}
+	}
+	if fw == nil {
+		interval := c.pollInterval
+		if interval <= 0 {
+			interval = defaultPollInterval
+		}
+		fw = newPollWatcher(interval, notify)
+	}
+
+	c.mutex.Lock()
+	c.currentSnapshot() // Baseline for the first diff
+	c.watching = true
+	dirs := c.watchDirs()
+	c.mutex.Unlock()
+
+	if err := fw.watch(dirs); err != nil {
+		fw.close()
+		c.mutex.Lock()
+		c.watching = false
+		c.mutex.Unlock()
+		return err
+	}
+
+	c.watcher.stop = make(chan struct{})
+	c.watcher.done = make(chan struct{})
+	go c.watchLoop(fw, events, forceReload, c.watcher.stop, c.watcher.done)
+	return nil
+}
+
+// Close stops the watcher started by Watch, waiting for a running OnChange callback to return
+func (c *Config) Close() error {
+	c.watcher.mutex.Lock()
+	stop, done := c.watcher.stop, c.watcher.done
+	c.watcher.stop, c.watcher.done = nil, nil
+	c.watcher.mutex.Unlock()
+
+	if stop == nil {
+		return nil
+	}
+	close(stop)
+	<-done
+
+	c.mutex.Lock()
+	c.watching = false
+	c.mutex.Unlock()
+	return nil
+}
+
+// watchLoop waits for notifications, reloads and dispatches change events until stopped
+func (c *Config) watchLoop(fw fileWatcher, events <-chan struct{}, forceReload bool, stop, done chan struct{}) {
+	defer close(done)
+	defer fw.close()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-events:
+		}
+
+		// Collect the rest of the burst a single save produces
+		timer := time.NewTimer(watchDebounce)
+	debounce:
+		for {
+			select {
+			case <-stop:
+				timer.Stop()
+				return
+			case <-events:
+			case <-timer.C:
+				break debounce
+			}
+		}
+
+		// Watch new directories before reloading, so files created in them from now on notify
+		c.mutex.RLock()
+		dirs := c.watchDirs()
+		c.mutex.RUnlock()
+		fw.watch(dirs)
+
+		event, changed := c.refresh(forceReload)
+		if changed {
+			c.dispatch(event)
+		}
+	}
+}
+
+// refresh reloads the configuration and reports what changed
+func (c *Config) refresh(force bool) (ChangeEvent, bool) {
+	c.mutex.Lock()
+	previous := c.current
+	if previous != nil && !force && !c.filesChanged() {
+		c.mutex.Unlock()
+		return ChangeEvent{}, false
+	}
+	c.reload()
+	c.lastCheck = time.Now()
+	c.stale = false
+	current := c.current
+	c.mutex.Unlock()
+
+	event := ChangeEvent{
+		Generation: current.generation,
+		Changes:    diffSnapshots(previous, current),
+		Err:        current.err,
+	}
+	return event, len(event.Changes) > 0 || event.Err != nil
+}
+
+// dispatch calls every registered callback with the event
+func (c *Config) dispatch(event ChangeEvent) {
+	c.watcher.mutex.Lock()
+	listeners := append([]func(ChangeEvent){}, c.watcher.listeners...)
+	c.watcher.mutex.Unlock()
+
+	for _, fn := range listeners {
+		fn(event)
+	}
+}
+
+// watchDirs lists the directories holding the current files plus every directory discovery searches, caller must hold a lock
+func (c *Config) watchDirs() []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	add := func(dir string) {
+		if dir != "" && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	if root, err := c.findProjectRoot(); err == nil && len(c.files) == 0 {
+		// New subdirectories may hold new files, so the whole tree is watched
+		c.walkSearchPaths(root, func(path, relPath string, info os.FileInfo) {
+			if info.IsDir() {
+				add(path)
+			}
+		})
+	}
+	if c.current != nil {
+		for file := range c.current.fileStamps {
+			add(filepath.Dir(file))
+		}
+	}
+
+	sort.Strings(dirs)
+	return dirs
+}
+
+// diffSnapshots compares every variable of two snapshots, masking secrets only in the changes it reports
+func diffSnapshots(previous, current *snapshot) []KeyChange {
+	oldValues, oldSecrets := flattenValues(previous)
+	newValues, newSecrets := flattenValues(current)
+
+	var keys []string
+	for key := range oldValues {
+		keys = append(keys, key)
+	}
+	for key := range newValues {
+		if _, exists := oldValues[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []KeyChange
+	for _, key := range keys {
+		oldValue, newValue := oldValues[key], newValues[key]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			if oldSecrets[key] && oldValue != nil {
+				oldValue = redactedValue
+			}
+			if newSecrets[key] && newValue != nil {
+				newValue = redactedValue
+			}
+			// Listeners get copies, the snapshot's arrays and tables stay untouched
+			changes = append(changes, KeyChange{Key: key, OldValue: deepCopyValue(oldValue), NewValue: deepCopyValue(newValue)})
+		}
+	}
+	return changes
+}
+
+// flattenSnapshot maps every variable to its value with secrets masked
+func flattenSnapshot(s *snapshot) map[string]interface{} {
+	values, secrets := flattenValues(s)
+	for key := range secrets {
+		values[key] = redactedValue
+	}
+	return values
+}
+
+// flattenValues maps every variable to its value, prefixing keys defined in more than one file, and lists the secret ones
+func flattenValues(s *snapshot) (map[string]interface{}, map[string]bool) {
+	values := make(map[string]interface{})
+	secrets := make(map[string]bool)
+	if s == nil || s.err != nil {
+		return values, secrets
+	}
+
+	fileKeys := make([][]string, len(s.files))
+	counts := make(map[string]int)
+	for i, fileData := range s.files {
+		collectKeys(fileData.Resolved, "", &fileKeys[i])
+		for _, key := range fileKeys[i] {
+			counts[key]++
+		}
+	}
+
+	for i, fileData := range s.files {
+		for _, key := range fileKeys[i] {
+			value, _ := resolveKey(fileData.Resolved, key)
+			secret := fileData.isSecret(key)
+			if counts[key] > 1 {
+				key = explicitKey(fileData.Prefix, key, s.explicitPrefixes)
+			}
+			values[key] = value
+			if secret {
+				secrets[key] = true
+			}
+		}
+	}
+	return values, secrets
+}
+
+// pollWatcher asks for a reload check on a fixed interval
+type pollWatcher struct {
+	stop chan struct{}
+	once sync.Once
+}
+
+func newPollWatcher(interval time.Duration, notify func()) *pollWatcher {
+	w := &pollWatcher{stop: make(chan struct{})}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				notify()
+			}
+		}
+	}()
+	return w
+}
+
+func (w *pollWatcher) watch(dirs []string) error {
+	return nil // Polling re-runs discovery, so there is nothing to register
+}
+
+func (w *pollWatcher) close() error {
+	w.once.Do(func() { close(w.stop) })
+	return nil
+}
+
+// OnChange registers a callback on the default Config
+func OnChange(fn func(ChangeEvent)) {
+	defaultConfig.OnChange(fn)
+}
+
+// Watch starts watching the default Config's files
+func Watch() error {
+	return defaultConfig.Watch()
+}
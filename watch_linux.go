@@ -0,0 +1,124 @@
+//go:build linux
+
+package tomv
+
+import (
+	"encoding/binary"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// inotifyMask covers writes, atomic renames and deletions of files in a watched directory
+const inotifyMask = syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB |
+	syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// inotifyWatcher watches directories with Linux inotify
+type inotifyWatcher struct {
+	fd     int
+	file   *os.File // Non-blocking, so Close unblocks the pending Read
+	notify func()
+
+	mutex   sync.Mutex
+	watches map[string]int // Directory -> watch descriptor
+	closed  bool
+}
+
+func newFileWatcher(notify func()) (fileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &inotifyWatcher{
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		notify:  notify,
+		watches: make(map[string]int),
+	}
+	go w.readEvents()
+	return w, nil
+}
+
+func (w *inotifyWatcher) watch(dirs []string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+
+	wanted := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		wanted[dir] = true
+	}
+
+	for dir, wd := range w.watches {
+		if !wanted[dir] {
+			syscall.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.watches, dir)
+		}
+	}
+
+	var firstErr error
+	for _, dir := range dirs {
+		if _, exists := w.watches[dir]; exists {
+			continue
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+			}
+			continue
+		}
+		w.watches[dir] = wd
+	}
+	return firstErr
+}
+
+func (w *inotifyWatcher) close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.file.Close()
+}
+
+// readEvents notifies for every batch of events that touches a TOML file or a directory
+func (w *inotifyWatcher) readEvents() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return // Closed
+		}
+
+		relevant := false
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			// struct inotify_event { int wd; uint32 mask; uint32 cookie; uint32 len; char name[]; }
+			mask := binary.NativeEndian.Uint32(buf[offset+4:])
+			nameLen := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+nameLen]), "\x00")
+			if mask&syscall.IN_ISDIR != 0 || isWatchedName(name) {
+				relevant = true
+			}
+			offset = nameStart + nameLen
+		}
+
+		if relevant {
+			w.notify()
+		}
+	}
+}
+
+// isWatchedName reports whether an event name can affect the configuration
+func isWatchedName(name string) bool {
+	return name == "" || name == ignoreFileName || strings.HasSuffix(strings.ToLower(name), ".toml")
+}
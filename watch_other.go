@@ -0,0 +1,10 @@
+//go:build !linux
+
+package tomv
+
+import "errors"
+
+// newFileWatcher has no native backend on this platform, Watch falls back to polling
+func newFileWatcher(notify func()) (fileWatcher, error) {
+	return nil, errors.New("native file watching is not supported on this platform")
+}